@@ -0,0 +1,68 @@
+package activity
+
+import (
+	"manga-catalog/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	View     = "view"
+	Favorite = "favorite"
+	// Оценок в каталоге пока нет: вес заведён заранее, а записывать событие
+	// начнёт эндпоинт оценок, когда он появится
+	Rating  = "rating"
+	Comment = "comment"
+)
+
+// Вес каждого вида события в рейтинге популярности
+var Weights = map[string]float64{
+	View:     1,
+	Comment:  3,
+	Rating:   4,
+	Favorite: 5,
+}
+
+type Window struct {
+	Name string
+	// Насколько далеко назад смотрим, 0 — за всё время
+	Range time.Duration
+	// Через сколько вклад события уменьшается вдвое, 0 — без затухания
+	HalfLife time.Duration
+}
+
+var Windows = []Window{
+	{Name: "day", Range: 24 * time.Hour, HalfLife: 6 * time.Hour},
+	{Name: "week", Range: 7 * 24 * time.Hour, HalfLife: 2 * 24 * time.Hour},
+	{Name: "month", Range: 30 * 24 * time.Hour, HalfLife: 7 * 24 * time.Hour},
+	{Name: "all"},
+}
+
+func FindWindow(name string) (Window, bool) {
+	for _, w := range Windows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+// Увеличивает счётчик событий в текущем часовом интервале
+func Record(db *gorm.DB, mangaID uint, kind string, n int) error {
+	return RecordAt(db, mangaID, kind, n, time.Now())
+}
+
+func RecordAt(db *gorm.DB, mangaID uint, kind string, n int, at time.Time) error {
+	row := models.MangaActivity{
+		MangaID: mangaID,
+		Bucket:  at.UTC().Truncate(time.Hour),
+		Kind:    kind,
+		Count:   n,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "manga_id"}, {Name: "bucket"}, {Name: "kind"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("manga_activities.count + EXCLUDED.count")}),
+	}).Create(&row).Error
+}
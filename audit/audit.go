@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"encoding/json"
+	"manga-catalog/models"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	Create   = "create"
+	Update   = "update"
+	Delete   = "delete"
+	Revoke   = "revoke"
+	Rotate   = "rotate"
+	Restore  = "restore"
+	Purge    = "purge"
+	Rollback = "rollback"
+	Approve  = "approve"
+	Reject   = "reject"
+	Publish  = "publish"
+)
+
+// Поля, которые меняются при каждом изменении и только зашумляют diff
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// Пишет запись аудита; вызывать в той же транзакции, что и само изменение
+func Record(tx *gorm.DB, c *gin.Context, action, entityType string, entityID uint, before, after interface{}) error {
+	entry := models.AuditEntry{
+		ActorID:    c.GetUint("user_id"),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		RequestID:  c.GetString("request_id"),
+		ClientIP:   c.ClientIP(),
+	}
+	if keyID := c.GetUint("api_key_id"); keyID != 0 {
+		entry.APIKeyID = &keyID
+	}
+	return write(tx, &entry, before, after)
+}
+
+// Для изменений из фоновых задач: актор 0, без запроса и IP
+func RecordSystem(tx *gorm.DB, action, entityType string, entityID uint, before, after interface{}) error {
+	entry := models.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	return write(tx, &entry, before, after)
+}
+
+func write(tx *gorm.DB, entry *models.AuditEntry, before, after interface{}) error {
+	beforeMap, err := toMap(before)
+	if err != nil {
+		return err
+	}
+	afterMap, err := toMap(after)
+	if err != nil {
+		return err
+	}
+
+	entry.Before = beforeMap
+	entry.After = afterMap
+	entry.Diff = Diff(beforeMap, afterMap)
+	return tx.Create(entry).Error
+}
+
+// Поле за полем: {"title": {"from": "...", "to": "..."}}
+func Diff(before, after map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for key, to := range after {
+		from, ok := before[key]
+		if ignoredFields[key] || (ok && reflect.DeepEqual(from, to)) {
+			continue
+		}
+		diff[key] = map[string]interface{}{"from": from, "to": to}
+	}
+	for key, from := range before {
+		if _, ok := after[key]; !ok && !ignoredFields[key] {
+			diff[key] = map[string]interface{}{"from": from, "to": nil}
+		}
+	}
+	return diff
+}
+
+// Приводит модель к виду, в котором она уходит в API
+func toMap(v interface{}) (map[string]interface{}, error) {
+	if v == nil || reflect.ValueOf(v).IsZero() {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
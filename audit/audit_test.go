@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"title": "Old", "genre": "Action", "updated_at": "t1", "note": "x"}
+	after := map[string]interface{}{"title": "New", "genre": "Action", "updated_at": "t2", "cover": "c.jpg"}
+
+	assert.Equal(t, map[string]interface{}{
+		"title": map[string]interface{}{"from": "Old", "to": "New"},
+		"cover": map[string]interface{}{"from": nil, "to": "c.jpg"},
+		"note":  map[string]interface{}{"from": "x", "to": nil},
+	}, Diff(before, after))
+}
+
+func TestDiffOnCreateAndDelete(t *testing.T) {
+	created := Diff(nil, map[string]interface{}{"title": "A"})
+	assert.Equal(t, map[string]interface{}{"title": map[string]interface{}{"from": nil, "to": "A"}}, created)
+
+	deleted := Diff(map[string]interface{}{"title": "A"}, nil)
+	assert.Equal(t, map[string]interface{}{"title": map[string]interface{}{"from": "A", "to": nil}}, deleted)
+}
+
+func TestToMapNil(t *testing.T) {
+	m, err := toMap(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"manga-catalog/models"
+
+	"gorm.io/gorm"
+)
+
+// Следующая по счёту ревизия; строка манги заблокирована вызывающим кодом
+func nextRevisionNumber(tx *gorm.DB, mangaID uint) (int, error) {
+	var last int
+	err := tx.Model(&models.MangaRevision{}).
+		Where("manga_id = ?", mangaID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&last).Error
+	return last + 1, err
+}
+
+// Пишет снимок манги новой ревизией; вызывать в той же транзакции, что и само изменение.
+// authorID 0 — изменение из фоновой задачи
+func WriteRevision(tx *gorm.DB, manga *models.Manga, authorID uint, rollbackOf *int) error {
+	number, err := nextRevisionNumber(tx, manga.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.MangaRevision{
+		MangaID:     manga.ID,
+		Number:      number,
+		Title:       manga.Title,
+		Description: manga.Description,
+		Genre:       manga.Genre,
+		Status:      manga.Status,
+		AuthorID:    authorID,
+		RollbackOf:  rollbackOf,
+	}).Error
+}
@@ -1,12 +1,23 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"time"
+
 	"github.com/go-resty/resty/v2"
 )
 
-var Client = resty.New()
+var Client = resty.New().SetTimeout(2 * time.Second)
+
+func userServiceURL() string {
+	if url := os.Getenv("USER_SERVICE_URL"); url != "" {
+		return url
+	}
+	return "http://user-service:8001"
+}
 
 type User struct {
 	ID       uint   `json:"user_id"`
@@ -14,13 +25,17 @@ type User struct {
 	Role     string `json:"role"`
 }
 
-func GetUserByID(id uint) (*User, error) {
+func GetUserByID(ctx context.Context, id uint) (*User, error) {
 	resp, err := Client.R().
-		Get(fmt.Sprintf("http://user-service:8001/api/users/%d", id))
+		SetContext(ctx).
+		Get(fmt.Sprintf("%s/api/users/%d", userServiceURL(), id))
 
 	if err != nil {
 		return nil, err
 	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("user-service вернул статус %d", resp.StatusCode())
+	}
 
 	var user User
 	if err := json.Unmarshal(resp.Body(), &user); err != nil {
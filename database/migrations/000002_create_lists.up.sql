@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER      NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL DEFAULT '',
+    visibility  VARCHAR(16)  NOT NULL DEFAULT 'private',
+    share_token VARCHAR(64)  NOT NULL UNIQUE,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_lists_user_id ON lists (user_id);
+
+CREATE TABLE IF NOT EXISTS list_items (
+    id       SERIAL PRIMARY KEY,
+    list_id  INTEGER NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    manga_id INTEGER NOT NULL REFERENCES mangas (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    note     TEXT    NOT NULL DEFAULT '',
+    UNIQUE (list_id, manga_id)
+);
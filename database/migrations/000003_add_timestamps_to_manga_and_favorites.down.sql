@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_favorites_manga_id;
+DROP INDEX IF EXISTS idx_favorites_user_created;
+
+ALTER TABLE favorites DROP COLUMN IF EXISTS created_at;
+ALTER TABLE mangas DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE mangas DROP COLUMN IF EXISTS created_at;
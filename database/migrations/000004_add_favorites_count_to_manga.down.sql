@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mangas_favorites_count;
+ALTER TABLE mangas DROP COLUMN IF EXISTS favorites_count;
+DROP INDEX IF EXISTS idx_favorites_user_manga;
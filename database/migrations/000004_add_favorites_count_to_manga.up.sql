@@ -0,0 +1,17 @@
+-- Дубликаты могли появиться из-за гонки в AddToFavorites
+DELETE FROM favorites a
+    USING favorites b
+WHERE a.user_id = b.user_id
+  AND a.manga_id = b.manga_id
+  AND a.id > b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_favorites_user_manga ON favorites (user_id, manga_id);
+
+ALTER TABLE mangas ADD COLUMN IF NOT EXISTS favorites_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE mangas m
+SET favorites_count = f.cnt
+FROM (SELECT manga_id, COUNT(*) AS cnt FROM favorites GROUP BY manga_id) f
+WHERE m.id = f.manga_id;
+
+CREATE INDEX IF NOT EXISTS idx_mangas_favorites_count ON mangas (favorites_count DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS manga_trendings;
+DROP TABLE IF EXISTS manga_activities;
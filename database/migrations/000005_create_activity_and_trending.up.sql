@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS manga_activities (
+    manga_id INTEGER     NOT NULL,
+    bucket   TIMESTAMPTZ NOT NULL,
+    kind     VARCHAR(16) NOT NULL,
+    count    INTEGER     NOT NULL DEFAULT 0,
+    PRIMARY KEY (manga_id, bucket, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_manga_activities_bucket ON manga_activities (bucket);
+
+CREATE TABLE IF NOT EXISTS manga_trendings (
+    time_window VARCHAR(16)      NOT NULL,
+    manga_id    INTEGER          NOT NULL,
+    score       DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMPTZ      NOT NULL,
+    PRIMARY KEY (time_window, manga_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_manga_trendings_score ON manga_trendings (time_window, score DESC);
@@ -0,0 +1 @@
+ALTER TABLE mangas ADD COLUMN IF NOT EXISTS views_count BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS manga_similars (
+    manga_id   INTEGER          NOT NULL,
+    similar_id INTEGER          NOT NULL,
+    score      DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (manga_id, similar_id)
+);
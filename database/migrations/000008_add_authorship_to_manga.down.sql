@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mangas_created_by;
+ALTER TABLE mangas DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE mangas DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,4 @@
+ALTER TABLE mangas ADD COLUMN IF NOT EXISTS created_by INTEGER;
+ALTER TABLE mangas ADD COLUMN IF NOT EXISTS updated_by INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_mangas_created_by ON mangas (created_by);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        VARCHAR(255) PRIMARY KEY,
+    user_id    INTEGER      NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ  NOT NULL,
+    reason     TEXT         NOT NULL DEFAULT '',
+    revoked_by INTEGER      NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    user_id        INTEGER PRIMARY KEY,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    reason         TEXT        NOT NULL DEFAULT '',
+    revoked_by     INTEGER     NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL,
+    prefix       VARCHAR(32)  NOT NULL UNIQUE,
+    hash         CHAR(64)     NOT NULL,
+    scopes       JSONB        NOT NULL DEFAULT '[]',
+    user_id      INTEGER      NOT NULL,
+    created_by   INTEGER      NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS audit_entries_no_update ON audit_entries;
+DROP FUNCTION IF EXISTS audit_entries_append_only();
+DROP TABLE IF EXISTS audit_entries;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id          BIGSERIAL PRIMARY KEY,
+    actor_id    INTEGER      NOT NULL,
+    api_key_id  INTEGER,
+    action      VARCHAR(32)  NOT NULL,
+    entity_type VARCHAR(32)  NOT NULL,
+    entity_id   INTEGER      NOT NULL,
+    request_id  VARCHAR(64)  NOT NULL DEFAULT '',
+    client_ip   VARCHAR(64)  NOT NULL DEFAULT '',
+    before      JSONB,
+    after       JSONB,
+    diff        JSONB,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_entries_entity ON audit_entries (entity_type, entity_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_actor ON audit_entries (actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_created_at ON audit_entries (created_at DESC);
+
+CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_entries_no_update
+    BEFORE UPDATE OR DELETE ON audit_entries
+    FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only();
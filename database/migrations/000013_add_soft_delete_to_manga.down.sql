@@ -0,0 +1,4 @@
+DELETE FROM mangas WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_mangas_deleted_at;
+ALTER TABLE mangas DROP COLUMN IF EXISTS deleted_at;
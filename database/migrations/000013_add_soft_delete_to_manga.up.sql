@@ -0,0 +1,3 @@
+ALTER TABLE mangas ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_mangas_deleted_at ON mangas (deleted_at);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS manga_revisions (
+    id          SERIAL PRIMARY KEY,
+    manga_id    INTEGER      NOT NULL,
+    number      INTEGER      NOT NULL,
+    title       VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL,
+    genre       VARCHAR(255) NOT NULL,
+    author_id   INTEGER      NOT NULL DEFAULT 0,
+    rollback_of INTEGER,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    UNIQUE (manga_id, number)
+);
+
+-- Текущее состояние существующей манги становится её первой ревизией
+INSERT INTO manga_revisions (manga_id, number, title, description, genre, author_id, created_at)
+SELECT id, 1, title, description, genre, COALESCE(updated_by, created_by, 0), updated_at
+FROM mangas
+ON CONFLICT DO NOTHING;
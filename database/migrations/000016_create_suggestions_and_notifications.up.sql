@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS manga_suggestions (
+    id          SERIAL PRIMARY KEY,
+    manga_id    INTEGER      NOT NULL,
+    user_id     INTEGER      NOT NULL,
+    title       VARCHAR(255),
+    description TEXT,
+    genre       VARCHAR(255),
+    comment     TEXT         NOT NULL DEFAULT '',
+    status      VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    reviewer_id INTEGER,
+    reason      TEXT         NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+-- Очередь модерации читается по статусу в порядке поступления
+CREATE INDEX IF NOT EXISTS idx_manga_suggestions_status ON manga_suggestions (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_manga_suggestions_user ON manga_suggestions (user_id);
+CREATE INDEX IF NOT EXISTS idx_manga_suggestions_manga ON manga_suggestions (manga_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER      NOT NULL,
+    type        VARCHAR(64)  NOT NULL,
+    message     TEXT         NOT NULL,
+    entity_type VARCHAR(64)  NOT NULL DEFAULT '',
+    entity_id   INTEGER      NOT NULL DEFAULT 0,
+    read_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_at DESC);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_mangas_scheduled;
+DROP INDEX IF EXISTS idx_mangas_status;
+
+ALTER TABLE mangas DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE mangas DROP COLUMN IF EXISTS status;
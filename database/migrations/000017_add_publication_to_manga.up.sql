@@ -0,0 +1,9 @@
+-- Уже существующая манга остаётся опубликованной
+ALTER TABLE mangas ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'published';
+ALTER TABLE mangas ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+
+UPDATE mangas SET publish_at = created_at WHERE publish_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_mangas_status ON mangas (status);
+-- Планировщик ищет только запланированные к публикации
+CREATE INDEX IF NOT EXISTS idx_mangas_scheduled ON mangas (publish_at) WHERE status = 'scheduled';
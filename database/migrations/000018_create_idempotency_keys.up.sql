@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key              VARCHAR(300) PRIMARY KEY,
+    request_hash     CHAR(64)     NOT NULL,
+    completed        BOOLEAN      NOT NULL DEFAULT FALSE,
+    response_status  INTEGER      NOT NULL DEFAULT 0,
+    response_headers JSONB,
+    response_body    BYTEA,
+    locked_at        TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at       TIMESTAMPTZ  NOT NULL,
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
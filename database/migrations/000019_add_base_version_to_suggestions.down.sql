@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_manga_suggestions_pending;
+ALTER TABLE manga_suggestions DROP COLUMN IF EXISTS base_version;
@@ -0,0 +1,15 @@
+ALTER TABLE manga_suggestions ADD COLUMN IF NOT EXISTS base_version BIGINT NOT NULL DEFAULT 1;
+
+-- Ожидающие предложения считаем сделанными к текущей версии манги
+UPDATE manga_suggestions s SET base_version = m.version
+FROM mangas m WHERE m.id = s.manga_id AND s.status = 'pending';
+
+-- У пользователя может быть только одно ожидающее предложение к манге: старые дубли отклоняем
+UPDATE manga_suggestions s SET status = 'rejected', reason = 'Заменено более новым предложением', reviewed_at = now()
+WHERE s.status = 'pending' AND EXISTS (
+    SELECT 1 FROM manga_suggestions n
+    WHERE n.manga_id = s.manga_id AND n.user_id = s.user_id AND n.status = 'pending' AND n.id > s.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_manga_suggestions_pending
+    ON manga_suggestions (manga_id, user_id) WHERE status = 'pending';
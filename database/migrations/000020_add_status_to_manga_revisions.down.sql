@@ -0,0 +1 @@
+ALTER TABLE manga_revisions DROP COLUMN IF EXISTS status;
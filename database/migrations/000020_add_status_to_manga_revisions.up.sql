@@ -0,0 +1,5 @@
+-- История статусов до миграции неизвестна, поэтому старым ревизиям ставим текущий статус манги
+ALTER TABLE manga_revisions ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'published';
+
+UPDATE manga_revisions r SET status = m.status
+FROM mangas m WHERE m.id = r.manga_id;
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"manga-catalog/audit"
+	"manga-catalog/database"
+	"manga-catalog/middleware"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func loadAPIKey(c *gin.Context) (*models.APIKey, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID ключа"})
+		return nil, false
+	}
+
+	var apiKey models.APIKey
+	if err := database.DB.First(&apiKey, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ключ не найден"})
+		return nil, false
+	}
+	return &apiKey, true
+}
+
+func CreateAPIKey(c *gin.Context) {
+	adminID := c.GetUint("user_id")
+
+	var body struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		UserID    uint       `json:"user_id"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Name == "" || len(body.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Название и области ключа обязательны"})
+		return
+	}
+	for _, scope := range body.Scopes {
+		if !middleware.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестная область: " + scope})
+			return
+		}
+	}
+	// По умолчанию ключ действует от имени создавшего его администратора
+	if body.UserID == 0 {
+		body.UserID = adminID
+	}
+
+	key, prefix, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании ключа"})
+		return
+	}
+
+	apiKey := models.APIKey{
+		Name:      body.Name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    body.Scopes,
+		UserID:    body.UserID,
+		CreatedBy: adminID,
+		ExpiresAt: body.ExpiresAt,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&apiKey).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Create, "api_key", apiKey.ID, nil, apiKey)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании ключа"})
+		return
+	}
+
+	// Ключ целиком показывается только один раз
+	c.JSON(http.StatusCreated, gin.H{"key": key, "api_key": apiKey})
+}
+
+func GetAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+	if err := database.DB.Order("created_at DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении ключей"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func RotateAPIKey(c *gin.Context) {
+	apiKey, ok := loadAPIKey(c)
+	if !ok {
+		return
+	}
+	if apiKey.RevokedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ключ отозван"})
+		return
+	}
+
+	key, prefix, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при ротации ключа"})
+		return
+	}
+
+	// Старое значение перестаёт работать сразу после ротации
+	before := *apiKey
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(apiKey).Updates(map[string]interface{}{
+			"prefix":       prefix,
+			"hash":         hash,
+			"last_used_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Rotate, "api_key", apiKey.ID, before, apiKey)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при ротации ключа"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"key": key, "api_key": apiKey})
+}
+
+func RevokeAPIKey(c *gin.Context) {
+	apiKey, ok := loadAPIKey(c)
+	if !ok {
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		before := *apiKey
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(apiKey).Update("revoked_at", time.Now()).Error; err != nil {
+				return err
+			}
+			return audit.Record(tx, c, audit.Revoke, "api_key", apiKey.ID, before, apiKey)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при отзыве ключа"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, apiKey)
+}
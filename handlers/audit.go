@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetAuditLog(c *gin.Context) {
+	limit, page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.AuditEntry{})
+
+	filters := map[string]string{
+		"action":      "action = ?",
+		"entity_type": "entity_type = ?",
+		"request_id":  "request_id = ?",
+	}
+	for param, condition := range filters {
+		if value := c.Query(param); value != "" {
+			query = query.Where(condition, value)
+		}
+	}
+
+	// Числовые фильтры проверяем сами, иначе ошибка приведения типа в БД превращается в 500
+	idFilters := map[string]string{
+		"actor_id":   "actor_id = ?",
+		"api_key_id": "api_key_id = ?",
+		"entity_id":  "entity_id = ?",
+	}
+	for param, condition := range idFilters {
+		if value := c.Query(param); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный параметр " + param})
+				return
+			}
+			query = query.Where(condition, id)
+		}
+	}
+
+	for param, condition := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		if value := c.Query(param); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты в " + param + ", ожидается RFC3339"})
+				return
+			}
+			query = query.Where(condition, at)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте total"})
+		return
+	}
+
+	var entries []models.AuditEntry
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении журнала"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  entries,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
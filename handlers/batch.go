@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"manga-catalog/database"
+	"manga-catalog/middleware"
+	"manga-catalog/models"
+	"manga-catalog/recommend"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+
+	maxBatchOperations = 500
+)
+
+type batchOperation struct {
+	Op      string          `json:"op"`
+	ID      uint            `json:"id"`
+	IfMatch string          `json:"if_match"` // обязателен для update и delete, как If-Match у одиночных запросов
+	Data    json.RawMessage `json:"data"`
+}
+
+type batchResult struct {
+	Index  int         `json:"index"`
+	Op     string      `json:"op"`
+	Status int         `json:"status"`
+	ID     uint        `json:"id,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Fields fieldErrors `json:"fields,omitempty"`
+}
+
+// Ошибка отдельной операции пакета с HTTP-статусом, который она получила бы как обычный запрос
+type batchError struct {
+	status  int
+	message string
+	fields  fieldErrors
+}
+
+func (e *batchError) Error() string {
+	return e.message
+}
+
+func validationError(fields fieldErrors) *batchError {
+	return &batchError{status: http.StatusBadRequest, message: "Ошибка валидации", fields: fields}
+}
+
+// Разбирает data операции и проверяет теги binding так же, как обычный запрос
+func decodeBatchData(data json.RawMessage, req interface{}) error {
+	if len(data) == 0 {
+		return &batchError{status: http.StatusBadRequest, message: "Нет data"}
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return validationError(fieldErrors{typeErr.Field: "неверный тип значения"})
+		}
+		return &batchError{status: http.StatusBadRequest, message: "Неверный формат data"}
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return err
+		}
+		fields := fieldErrors{}
+		for _, fe := range validationErrs {
+			fields[fe.Field()] = validationMessage(fe)
+		}
+		return validationError(fields)
+	}
+	return nil
+}
+
+// Загружает мангу для update/delete с теми же проверками, что у одиночных запросов
+func loadBatchManga(tx *gorm.DB, c *gin.Context, op batchOperation, anyPermission string) (*models.Manga, error) {
+	var manga models.Manga
+	if err := tx.First(&manga, op.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &batchError{status: http.StatusNotFound, message: "Манга не найдена"}
+		}
+		return nil, err
+	}
+	if !canModify(c, manga.Authorship, anyPermission) {
+		return nil, &batchError{status: http.StatusForbidden, message: "Можно изменять только свою мангу"}
+	}
+	if op.IfMatch == "" {
+		return nil, &batchError{status: http.StatusPreconditionRequired, message: "Нужен if_match"}
+	}
+	if !versionMatches(op.IfMatch, manga) {
+		return nil, errVersionConflict
+	}
+	return &manga, nil
+}
+
+// Выполняет одну операцию; similar сообщает, нужно ли пересчитать похожие тайтлы
+func runBatchOperation(tx *gorm.DB, c *gin.Context, op batchOperation, result *batchResult) (similar bool, err error) {
+	switch op.Op {
+	case "create":
+		if !middleware.HasPermission(c, middleware.PermMangaCreate) {
+			return false, &batchError{status: http.StatusForbidden, message: "Недостаточно прав"}
+		}
+		var req mangaCreateRequest
+		if err := decodeBatchData(op.Data, &req); err != nil {
+			return false, err
+		}
+		manga, fields := req.manga(c.GetUint("user_id"))
+		if fields != nil {
+			return false, validationError(fields)
+		}
+		if err := insertManga(tx, c, &manga); err != nil {
+			return false, err
+		}
+		result.ID, result.Status = manga.ID, http.StatusCreated
+		return true, nil
+
+	case "update":
+		if !middleware.HasPermission(c, middleware.PermMangaUpdate) {
+			return false, &batchError{status: http.StatusForbidden, message: "Недостаточно прав"}
+		}
+		result.ID = op.ID
+		var req mangaUpdateRequest
+		if err := decodeBatchData(op.Data, &req); err != nil {
+			return false, err
+		}
+		changes, fields := req.changes()
+		if fields != nil {
+			return false, validationError(fields)
+		}
+		manga, err := loadBatchManga(tx, c, op, middleware.PermMangaUpdateAny)
+		if err != nil {
+			return false, err
+		}
+		if similar, err = updateManga(tx, c, manga, changes, nil); err != nil {
+			return false, err
+		}
+		result.Status = http.StatusOK
+		return similar, nil
+
+	case "delete":
+		if !middleware.HasPermission(c, middleware.PermMangaDelete) {
+			return false, &batchError{status: http.StatusForbidden, message: "Недостаточно прав"}
+		}
+		result.ID = op.ID
+		manga, err := loadBatchManga(tx, c, op, middleware.PermMangaDeleteAny)
+		if err != nil {
+			return false, err
+		}
+		if err := deleteManga(tx, c, manga); err != nil {
+			return false, err
+		}
+		result.Status = http.StatusOK
+		return false, nil
+	}
+
+	return false, &batchError{status: http.StatusBadRequest, message: "Неизвестная операция: " + op.Op}
+}
+
+func fillBatchError(result *batchResult, err error) {
+	var be *batchError
+	switch {
+	case errors.As(err, &be):
+		result.Status, result.Error, result.Fields = be.status, be.message, be.fields
+	case errors.Is(err, errVersionConflict):
+		result.Status, result.Error = http.StatusPreconditionFailed, "Манга была изменена, обновите данные"
+	default:
+		result.Status, result.Error = http.StatusInternalServerError, "Ошибка базы данных"
+	}
+}
+
+// Пакет операций над каталогом. В режиме atomic всё выполняется в одной транзакции
+// и откатывается при первой ошибке, в best_effort каждая операция применяется отдельно
+func BatchManga(c *gin.Context) {
+	var body struct {
+		Mode       string           `json:"mode"`
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса"})
+		return
+	}
+	if body.Mode == "" {
+		body.Mode = batchAtomic
+	}
+	if body.Mode != batchAtomic && body.Mode != batchBestEffort {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Режим должен быть atomic или best_effort"})
+		return
+	}
+	if len(body.Operations) == 0 || len(body.Operations) > maxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нужно от 1 до 500 операций"})
+		return
+	}
+
+	results := make([]batchResult, 0, len(body.Operations))
+	var similarIDs []uint
+	failed := 0
+
+	if body.Mode == batchAtomic {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			for i, op := range body.Operations {
+				result := batchResult{Index: i, Op: op.Op}
+				similar, err := runBatchOperation(tx, c, op, &result)
+				if err != nil {
+					fillBatchError(&result, err)
+					results = append(results, result)
+					return err
+				}
+				if similar {
+					similarIDs = append(similarIDs, result.ID)
+				}
+				results = append(results, result)
+			}
+			return nil
+		})
+		if err != nil {
+			// Ни одна операция не применилась: успешные до ошибки откачены, остальные не запускались
+			failedAt := len(results) - 1
+			if results[failedAt].Status < http.StatusBadRequest {
+				// Все операции прошли, но не удалось зафиксировать транзакцию
+				failedAt = len(results)
+			}
+			for i := 0; i < failedAt; i++ {
+				results[i] = batchResult{Index: i, Op: results[i].Op, Status: http.StatusFailedDependency, Error: "Не применено: пакет откачен"}
+			}
+			for i := failedAt + 1; i < len(body.Operations); i++ {
+				results = append(results, batchResult{
+					Index:  i,
+					Op:     body.Operations[i].Op,
+					Status: http.StatusFailedDependency,
+					Error:  "Не выполнено: пакет остановлен на ошибке",
+				})
+			}
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"mode":      body.Mode,
+				"committed": false,
+				"results":   results,
+			})
+			return
+		}
+	} else {
+		for i, op := range body.Operations {
+			result := batchResult{Index: i, Op: op.Op}
+			err := database.DB.Transaction(func(tx *gorm.DB) error {
+				similar, err := runBatchOperation(tx, c, op, &result)
+				if err == nil && similar {
+					similarIDs = append(similarIDs, result.ID)
+				}
+				return err
+			})
+			if err != nil {
+				fillBatchError(&result, err)
+				failed++
+			}
+			results = append(results, result)
+		}
+	}
+
+	if len(similarIDs) > 0 {
+		recommend.RefreshSimilar(similarIDs...)
+	}
+
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, gin.H{
+		"mode":      body.Mode,
+		"committed": true,
+		"results":   results,
+	})
+}
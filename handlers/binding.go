@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Ошибки валидации по полям запроса: {"title": "обязательное поле"}
+type fieldErrors map[string]string
+
+// Строковое поле запроса, которое отличает «не передано» от null и пустого значения.
+// В форме null выразить нельзя, поэтому пустое значение там тоже означает очистку
+type optionalString struct {
+	present bool
+	null    bool
+	value   string
+}
+
+func (o *optionalString) UnmarshalJSON(data []byte) error {
+	o.present = true
+	if string(data) == "null" {
+		o.null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.value)
+}
+
+func (o *optionalString) UnmarshalParam(param string) error {
+	o.present = true
+	o.value = param
+	return nil
+}
+
+// Значение для записи: nil, если поле не передано; пустая строка, если его очищают
+func (o optionalString) ptr() *string {
+	if !o.present {
+		return nil
+	}
+	value := o.value
+	return &value
+}
+
+func (o optionalString) cleared() bool {
+	return o.present && (o.null || o.value == "")
+}
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// В ошибках показываем имена полей так, как их присылает клиент
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// Правила вроде max=255 проверяют само значение, а не обёртку
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		o := field.Interface().(optionalString)
+		if !o.present || o.null {
+			return nil
+		}
+		return o.value
+	}, optionalString{})
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "обязательное поле"
+	case "max":
+		return "не длиннее " + fe.Param() + " символов"
+	case "oneof":
+		return "допустимые значения: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	}
+	return "недопустимое значение"
+}
+
+// Разбирает JSON или форму по Content-Type; при ошибке сам отвечает 400
+func bindRequest(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBind(req); err != nil {
+		respondBindError(c, err)
+		return false
+	}
+	return true
+}
+
+// Ошибки валидатора и типов JSON отдаёт по полям, остальное — общим сообщением
+func respondBindError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := fieldErrors{}
+		for _, fe := range validationErrs {
+			fields[fe.Field()] = validationMessage(fe)
+		}
+		respondValidation(c, fields)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		respondValidation(c, fieldErrors{typeErr.Field: "неверный тип значения"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса"})
+	}
+}
+
+func respondValidation(c *gin.Context, fields fieldErrors) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка валидации", "fields": fields})
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"manga-catalog/activity"
+	"manga-catalog/audit"
 	"manga-catalog/database"
 	"manga-catalog/models"
 	"net/http"
@@ -8,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func AddComment(c *gin.Context) {
@@ -28,6 +31,12 @@ func AddComment(c *gin.Context) {
 		return
 	}
 
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
 	comment := models.Comment{
 		MangaID:   uint(mangaID),
 		UserID:    userID,
@@ -35,7 +44,16 @@ func AddComment(c *gin.Context) {
 		CreatedAt: time.Now(),
 	}
 
-	if err := database.DB.Create(&comment).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, c, audit.Create, "comment", comment.ID, nil, comment); err != nil {
+			return err
+		}
+		return activity.Record(tx, comment.MangaID, activity.Comment, 1)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при добавлении"})
 		return
 	}
@@ -44,14 +62,35 @@ func AddComment(c *gin.Context) {
 }
 
 func GetComments(c *gin.Context) {
-	mangaID := c.Param("id")
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	// Комментарии к манге из корзины скрыты вместе с ней
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
 
 	var comments []models.Comment
-	err := database.DB.Where("manga_id = ?", mangaID).Order("created_at DESC").Find(&comments).Error
+	err = database.DB.Where("manga_id = ?", mangaID).Order("created_at DESC").Find(&comments).Error
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении комментариев"})
 		return
 	}
 
-	c.JSON(http.StatusOK, comments)
+	userID := c.GetUint("user_id")
+	response := make([]commentResponse, len(comments))
+	for i, comment := range comments {
+		response[i] = commentResponse{Comment: comment}
+		if userID != 0 {
+			isOwn := comment.UserID == userID
+			response[i].IsOwn = &isOwn
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
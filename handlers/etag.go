@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"manga-catalog/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Манга изменилась после того, как клиент её прочитал
+var errVersionConflict = errors.New("версия манги устарела")
+
+// ETag версии: описывает только редактируемые поля манги. Его возвращают изменения манги
+// и по нему проверяется If-Match
+func mangaETag(manga models.Manga) string {
+	return fmt.Sprintf(`"%d.%d"`, manga.ID, manga.Version)
+}
+
+// ETag ответа на чтение: версия плюс хэш всего тела, включая счётчики и блок viewer.
+// If-None-Match сравнивает его целиком, If-Match — только версию, иначе каждый просмотр
+// ломал бы If-Match у редакторов
+func representationETag(manga models.Manga, body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`"%d.%d-%s"`, manga.ID, manga.Version, hex.EncodeToString(sum[:8]))
+}
+
+// Сравнивает ETag со списком из If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "W/"))
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Сильное сравнение If-Match с версией манги: годится и ETag версии, и ETag чтения
+func versionMatches(header string, manga models.Manga) bool {
+	prefix := strings.TrimSuffix(mangaETag(manga), `"`) + "-"
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == mangaETag(manga) || strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверяет If-Match перед изменением, при ошибке сам отвечает 428 или 412
+func checkIfMatch(c *gin.Context, manga models.Manga) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Нужен заголовок If-Match"})
+		return false
+	}
+	if !versionMatches(header, manga) {
+		respondVersionConflict(c, manga)
+		return false
+	}
+	return true
+}
+
+func respondVersionConflict(c *gin.Context, manga models.Manga) {
+	c.Header("ETag", mangaETag(manga))
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Манга была изменена, обновите данные"})
+}
+
+// Отвечает телом или 304, если у клиента то же представление. Тело зависит от пользователя,
+// поэтому кэши должны различать запросы по учётным данным
+func respondRepresentation(c *gin.Context, manga models.Manga, body []byte) {
+	etag := representationETag(manga, body)
+	c.Header("ETag", etag)
+	c.Header("Vary", "Authorization, X-API-Key")
+	if header := c.GetHeader("If-None-Match"); header != "" && etagMatches(header, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
@@ -11,6 +11,7 @@ func GetAllGenres(c *gin.Context) {
 	var genres []string
 	err := database.DB.
 		Model(&models.Manga{}).
+		Scopes(listedManga).
 		Distinct("genre").
 		Pluck("genre", &genres).Error
 
@@ -31,6 +32,7 @@ func GetGenresWithCount(c *gin.Context) {
 
 	err := database.DB.
 		Model(&models.Manga{}).
+		Scopes(listedManga).
 		Select("genre, COUNT(*) as count").
 		Group("genre").
 		Scan(&result).Error
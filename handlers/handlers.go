@@ -1,31 +1,82 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"manga-catalog/activity"
+	"manga-catalog/audit"
 	"manga-catalog/client"
 	"manga-catalog/database"
+	"manga-catalog/middleware"
 	"manga-catalog/models"
+	"manga-catalog/recommend"
+	"manga-catalog/views"
 	"net/http"
 	"strconv"
+	"time"
 )
 
-func GetMangaList(c *gin.Context) {
-	var manga []models.Manga
-
+// Разбирает limit и page из query, при ошибке сам отвечает 400
+func parsePagination(c *gin.Context) (limit, page int, ok bool) {
 	limitStr := c.DefaultQuery("limit", "10")
 	pageStr := c.DefaultQuery("page", "1")
-	genre := c.Query("genre")
 
 	limit, err1 := strconv.Atoi(limitStr)
 	page, err2 := strconv.Atoi(pageStr)
 	if err1 != nil || err2 != nil || limit <= 0 || page <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверные параметры пагинации"})
+		return 0, 0, false
+	}
+	return limit, page, true
+}
+
+// Порядок сортировки каталога по параметру sort
+var mangaSorts = map[string]string{
+	"id":        "id ASC",
+	"title":     "title ASC",
+	"newest":    "created_at DESC",
+	"favorites": "favorites_count DESC",
+}
+
+func GetMangaList(c *gin.Context) {
+	var manga []models.Manga
+
+	genre := c.Query("genre")
+
+	limit, page, ok := parsePagination(c)
+	if !ok {
 		return
 	}
 	offset := (page - 1) * limit
 
+	order, ok := mangaSorts[c.DefaultQuery("sort", "id")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный параметр сортировки"})
+		return
+	}
+
 	// Запрос с фильтрацией
 	query := database.DB.Model(&models.Manga{})
+	switch status := c.Query("status"); {
+	case status == "":
+		query = query.Scopes(listedManga)
+	case status == "all" || models.ValidMangaStatus(status):
+		if status != "all" {
+			query = query.Where("status = ?", status)
+		}
+		// Чужие неопубликованные тайтлы видят только модераторы
+		if !middleware.HasPermission(c, middleware.PermMangaUpdateAny) {
+			query = query.Where("status = ? OR created_by = ?", models.MangaPublished, c.GetUint("user_id"))
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный статус публикации"})
+		return
+	}
 	if genre != "" {
 		query = query.Where("genre = ?", genre)
 	}
@@ -38,58 +89,109 @@ func GetMangaList(c *gin.Context) {
 	}
 
 	// Получаем текущую страницу
-	if err := query.Limit(limit).Offset(offset).Find(&manga).Error; err != nil {
+	if err := query.Order(order).Order("id").Limit(limit).Offset(offset).Find(&manga).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка базы данных"})
+		return
+	}
+
+	data, err := withViewers(c.GetUint("user_id"), manga)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка базы данных"})
 		return
 	}
 
 	// Возвращаем данные + total
 	c.JSON(http.StatusOK, gin.H{
-		"data":  manga,
+		"data":  data,
 		"page":  page,
 		"limit": limit,
 		"total": total,
 	})
 }
 
-func CreateManga(c *gin.Context) {
-	title := c.PostForm("title")
-	description := c.PostForm("description")
-	genre := c.PostForm("genre")
+type mangaCreateRequest struct {
+	Title       string `json:"title" form:"title" binding:"required,max=255"`
+	Description string `json:"description" form:"description" binding:"required"`
+	Genre       string `json:"genre" form:"genre" binding:"required,max=255"`
+	Status      string `json:"status" form:"status"`
+	PublishAt   string `json:"publish_at" form:"publish_at"`
+}
 
-	if title == "" || description == "" || genre == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Все поля обязательны"})
+func CreateManga(c *gin.Context) {
+	var req mangaCreateRequest
+	if !bindRequest(c, &req) {
 		return
 	}
 
-	manga := models.Manga{
-		Title:       title,
-		Description: description,
-		Genre:       genre,
+	manga, fields := req.manga(c.GetUint("user_id"))
+	if fields != nil {
+		respondValidation(c, fields)
+		return
 	}
 
-	if err := database.DB.Create(&manga).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return insertManga(tx, c, &manga)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при добавлении манги"})
 		return
 	}
 
+	recommend.RefreshSimilar(manga.ID)
+
+	c.Header("ETag", mangaETag(manga))
 	c.JSON(http.StatusCreated, manga)
 }
 
+// Автор может менять свою запись, для чужих нужно право anyPermission
+func canModify(c *gin.Context, record models.Authorship, anyPermission string) bool {
+	return middleware.HasPermission(c, anyPermission) || record.IsCreatedBy(c.GetUint("user_id"))
+}
+
 func GetMangaByID(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
 	var manga models.Manga
 
-	if err := database.DB.First(&manga, id).Error; err != nil {
+	if err := findVisibleManga(c, &manga, id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
 		return
 	}
 
-	c.JSON(http.StatusOK, manga)
+	views.Default.Hit(views.Target{Kind: views.Manga, ID: manga.ID}, viewerKey(c))
+
+	response, err := withViewers(c.GetUint("user_id"), []models.Manga{manga})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка базы данных"})
+		return
+	}
+	body, err := json.Marshal(response[0])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении манги"})
+		return
+	}
+
+	respondRepresentation(c, manga, body)
+}
+
+// Идентификатор зрителя для дедупликации просмотров. ClientIP доверяет X-Forwarded-For
+// только от прокси из TRUSTED_PROXIES, поэтому подменить IP заголовком нельзя
+func viewerKey(c *gin.Context) string {
+	if userID := c.GetUint("user_id"); userID != 0 {
+		return "u:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return "ip:" + c.ClientIP()
 }
 
 func UpdateManga(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
 	var manga models.Manga
 
 	if err := database.DB.First(&manga, id).Error; err != nil {
@@ -97,30 +199,54 @@ func UpdateManga(c *gin.Context) {
 		return
 	}
 
-	title := c.PostForm("title")
-	description := c.PostForm("description")
-	genre := c.PostForm("genre")
+	if !canModify(c, manga.Authorship, middleware.PermMangaUpdateAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно редактировать только свою мангу"})
+		return
+	}
 
-	if title != "" {
-		manga.Title = title
+	if !checkIfMatch(c, manga) {
+		return
 	}
-	if description != "" {
-		manga.Description = description
+
+	var req mangaUpdateRequest
+	if !bindRequest(c, &req) {
+		return
 	}
-	if genre != "" {
-		manga.Genre = genre
+	changes, fields := req.changes()
+	if fields != nil {
+		respondValidation(c, fields)
+		return
 	}
 
-	if err := database.DB.Save(&manga).Error; err != nil {
+	var similarChanged bool
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		similarChanged, err = updateManga(tx, c, &manga, changes, nil)
+		return err
+	})
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, manga)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении манги"})
 		return
 	}
 
+	if similarChanged {
+		recommend.RefreshSimilar(manga.ID)
+	}
+
+	c.Header("ETag", mangaETag(manga))
 	c.JSON(http.StatusOK, manga)
 }
 
 func DeleteManga(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
 	var manga models.Manga
 
 	if err := database.DB.First(&manga, id).Error; err != nil {
@@ -128,12 +254,29 @@ func DeleteManga(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&manga).Error; err != nil {
+	if !canModify(c, manga.Authorship, middleware.PermMangaDeleteAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно удалять только свою мангу"})
+		return
+	}
+
+	if !checkIfMatch(c, manga) {
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteManga(tx, c, &manga)
+	})
+	if errors.Is(err, errVersionConflict) {
+		database.DB.First(&manga, manga.ID)
+		respondVersionConflict(c, manga)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении манги"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Манга успешно удалена"})
+	c.JSON(http.StatusOK, gin.H{"message": "Манга перемещена в корзину"})
 }
 
 func AddToFavorites(c *gin.Context) {
@@ -146,58 +289,134 @@ func AddToFavorites(c *gin.Context) {
 	}
 
 	var manga models.Manga
-	if err := database.DB.First(&manga, mangaID).Error; err != nil {
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
 		return
 	}
 
-	var existing models.Favorite
-	err = database.DB.Where("user_id = ? AND manga_id = ?", userID, mangaID).First(&existing).Error
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Манга уже в избранном"})
+	// Добавление и счётчик меняются в одной транзакции, чтобы не расходиться
+	added := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		favorite := models.Favorite{
+			UserID:  userID,
+			MangaID: uint(mangaID),
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		added = true
+		if err := audit.Record(tx, c, audit.Create, "favorite", favorite.ID, nil, favorite); err != nil {
+			return err
+		}
+		err := tx.Model(&models.Manga{}).
+			Where("id = ?", mangaID).
+			UpdateColumn("favorites_count", gorm.Expr("favorites_count + 1")).Error
+		if err != nil {
+			return err
+		}
+		return activity.Record(tx, uint(mangaID), activity.Favorite, 1)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при добавлении в избранное"})
 		return
 	}
-
-	favorite := models.Favorite{
-		UserID:  userID,
-		MangaID: uint(mangaID),
-	}
-
-	if err := database.DB.Create(&favorite).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при добавлении в избранное"})
+	if !added {
+		c.JSON(http.StatusConflict, gin.H{"error": "Манга уже в избранном"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Манга добавлена в избранное"})
 }
 
+// Порядок сортировки избранного по параметру sort
+var favoriteSorts = map[string]string{
+	"added":   "favorites.created_at DESC",
+	"title":   "mangas.title ASC",
+	"updated": "mangas.updated_at DESC",
+}
+
+// Сколько ждём user-service, прежде чем отдать избранное без имени пользователя
+const userLookupTimeout = 300 * time.Millisecond
+
+type favoriteRow struct {
+	models.Manga `gorm:"embedded"`
+	FavoritedAt  time.Time `json:"favorited_at"`
+	Total        int64     `json:"-"`
+}
+
 func GetFavorites(c *gin.Context) {
 	userID := c.MustGet("user_id").(uint)
 
-	user, err := client.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить данные пользователя"})
+	limit, page, ok := parsePagination(c)
+	if !ok {
 		return
 	}
 
-	var favorites []models.Favorite
-	if err := database.DB.Where("user_id = ?", userID).Find(&favorites).Error; err != nil {
+	order, ok := favoriteSorts[c.DefaultQuery("sort", "added")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный параметр сортировки"})
+		return
+	}
+
+	// Данные пользователя запрашиваем параллельно с БД и не ждём дольше таймаута
+	var userCh chan *client.User
+	if c.DefaultQuery("include_user", "true") == "true" {
+		userCh = make(chan *client.User, 1)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), userLookupTimeout)
+		defer cancel()
+		go func() {
+			user, err := client.GetUserByID(ctx, userID)
+			if err != nil {
+				log.Printf("Не удалось получить пользователя %d: %v", userID, err)
+			}
+			userCh <- user
+		}()
+	}
+
+	rows := []favoriteRow{}
+	err := database.DB.
+		Model(&models.Manga{}).
+		Select("mangas.*, favorites.created_at AS favorited_at, COUNT(*) OVER() AS total").
+		Joins("JOIN favorites ON favorites.manga_id = mangas.id").
+		Where("favorites.user_id = ?", userID).
+		Scopes(visibleManga(c)).
+		Order(order).
+		Order("mangas.id").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Scan(&rows).Error
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении избранного"})
 		return
 	}
 
-	var mangaList []models.Manga
-	for _, fav := range favorites {
-		var manga models.Manga
-		if err := database.DB.First(&manga, fav.MangaID).Error; err == nil {
-			mangaList = append(mangaList, manga)
+	var total int64
+	if len(rows) > 0 {
+		total = rows[0].Total
+	} else if page > 1 {
+		// За пределами последней страницы оконная функция ничего не вернёт
+		database.DB.Model(&models.Manga{}).
+			Joins("JOIN favorites ON favorites.manga_id = mangas.id").
+			Where("favorites.user_id = ?", userID).
+			Scopes(visibleManga(c)).
+			Count(&total)
+	}
+
+	response := gin.H{
+		"user":      nil,
+		"favorites": rows,
+		"page":      page,
+		"limit":     limit,
+		"total":     total,
+	}
+	if userCh != nil {
+		if user := <-userCh; user != nil {
+			response["user"] = user.Username
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"user":      user.Username,
-		"favorites": mangaList,
-	})
+	c.JSON(http.StatusOK, response)
 }
 
 func RemoveFromFavorites(c *gin.Context) {
@@ -209,10 +428,60 @@ func RemoveFromFavorites(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Where("user_id = ? AND manga_id = ?", userID, mangaID).Delete(&models.Favorite{}).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var favorites []models.Favorite
+		res := tx.Clauses(clause.Returning{}).Where("user_id = ? AND manga_id = ?", userID, mangaID).Delete(&favorites)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		for _, favorite := range favorites {
+			if err := audit.Record(tx, c, audit.Delete, "favorite", favorite.ID, favorite, nil); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Manga{}).
+			Where("id = ?", mangaID).
+			UpdateColumn("favorites_count", gorm.Expr("GREATEST(favorites_count - ?, 0)", res.RowsAffected)).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении из избранного"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Манга удалена из избранного"})
 }
+
+func GetFavoriteStats(c *gin.Context) {
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	var stats struct {
+		Last7Days  int64
+		Last30Days int64
+	}
+	err = database.DB.Model(&models.Favorite{}).
+		Select(`COUNT(*) FILTER (WHERE created_at >= now() - interval '7 days') AS last7_days,
+			COUNT(*) FILTER (WHERE created_at >= now() - interval '30 days') AS last30_days`).
+		Where("manga_id = ?", mangaID).
+		Scan(&stats).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении статистики"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"manga_id":        manga.ID,
+		"favorites_count": manga.FavoritesCount,
+		"last_7_days":     stats.Last7Days,
+		"last_30_days":    stats.Last30Days,
+	})
+}
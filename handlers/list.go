@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"errors"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errListItemExists = errors.New("манга уже в списке")
+
+type listBody struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+func validVisibility(v string) bool {
+	return v == models.ListPrivate || v == models.ListUnlisted || v == models.ListPublic
+}
+
+// Загружает список вместе с элементами, отсортированными по позиции
+func loadList(db *gorm.DB, query interface{}, args ...interface{}) (*models.List, error) {
+	var list models.List
+	// Манга из корзины и неопубликованная в списках не показывается, но её строки остаются в list_items
+	// со своими позициями и снова появятся после восстановления или публикации
+	err := db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN mangas ON mangas.id = list_items.manga_id AND mangas.deleted_at IS NULL AND mangas.status IN ?",
+			[]string{models.MangaPublished, models.MangaUnlisted}).
+			Order("position ASC")
+	}).Preload("Items.Manga").Where(query, args...).First(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Список, принадлежащий текущему пользователю
+func loadOwnList(c *gin.Context) (*models.List, bool) {
+	userID := c.GetUint("user_id")
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID списка"})
+		return nil, false
+	}
+
+	list, err := loadList(database.DB, "id = ?", listID)
+	if err != nil || list.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Список не найден"})
+		return nil, false
+	}
+	return list, true
+}
+
+// Чужим пользователям токен доступа не показываем
+func publicView(list *models.List, userID uint) *models.List {
+	if list.UserID != userID {
+		list.ShareToken = ""
+	}
+	return list
+}
+
+func CreateList(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var body listBody
+	if err := c.ShouldBindJSON(&body); err != nil || body.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Название списка обязательно"})
+		return
+	}
+	if body.Visibility == "" {
+		body.Visibility = models.ListPrivate
+	}
+	if !validVisibility(body.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверная видимость списка"})
+		return
+	}
+
+	list := models.List{
+		UserID:      userID,
+		Name:        body.Name,
+		Description: body.Description,
+		Visibility:  body.Visibility,
+		ShareToken:  uuid.New().String(),
+	}
+
+	if err := database.DB.Create(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании списка"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+func GetMyLists(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var lists []models.List
+	if err := database.DB.Where("user_id = ?", userID).Order("updated_at DESC").Find(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении списков"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+func GetUserPublicLists(c *gin.Context) {
+	ownerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	var lists []models.List
+	err = database.DB.Omit("share_token").
+		Where("user_id = ? AND visibility = ?", ownerID, models.ListPublic).
+		Order("updated_at DESC").
+		Find(&lists).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении списков"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+func GetList(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID списка"})
+		return
+	}
+
+	list, err := loadList(database.DB, "id = ?", listID)
+	if err != nil || (list.Visibility != models.ListPublic && list.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Список не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, publicView(list, userID))
+}
+
+func GetSharedList(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	token := c.Param("token")
+
+	list, err := loadList(database.DB, "share_token = ? AND visibility <> ?", token, models.ListPrivate)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Список не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, publicView(list, userID))
+}
+
+func UpdateList(c *gin.Context) {
+	list, ok := loadOwnList(c)
+	if !ok {
+		return
+	}
+
+	var body listBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверное тело запроса"})
+		return
+	}
+
+	if body.Name != "" {
+		list.Name = body.Name
+	}
+	if body.Description != "" {
+		list.Description = body.Description
+	}
+	if body.Visibility != "" {
+		if !validVisibility(body.Visibility) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверная видимость списка"})
+			return
+		}
+		// При закрытии доступа старая ссылка должна перестать работать
+		if body.Visibility == models.ListPrivate && list.Visibility != models.ListPrivate {
+			list.ShareToken = uuid.New().String()
+		}
+		list.Visibility = body.Visibility
+	}
+
+	if err := database.DB.Omit("Items").Save(list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении списка"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func DeleteList(c *gin.Context) {
+	list, ok := loadOwnList(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", list.ID).Delete(&models.ListItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.List{}, list.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении списка"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Список удалён"})
+}
+
+func AddListItem(c *gin.Context) {
+	list, ok := loadOwnList(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		MangaID uint   `json:"manga_id"`
+		Note    string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.MangaID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID манги обязателен"})
+		return
+	}
+
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, body.MangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	item := models.ListItem{
+		ListID:  list.ID,
+		MangaID: body.MangaID,
+		Note:    body.Note,
+	}
+
+	// Дубликаты и последнюю позицию считаем по всем строкам списка, включая скрытую мангу
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.List{}, list.ID).Error; err != nil {
+			return err
+		}
+		var exists int64
+		if err := tx.Model(&models.ListItem{}).Where("list_id = ? AND manga_id = ?", list.ID, body.MangaID).Count(&exists).Error; err != nil {
+			return err
+		}
+		if exists > 0 {
+			return errListItemExists
+		}
+		if err := tx.Model(&models.ListItem{}).Where("list_id = ?", list.ID).
+			Select("COALESCE(MAX(position), 0) + 1").Scan(&item.Position).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		return tx.Model(list).Update("updated_at", gorm.Expr("now()")).Error
+	})
+	if errors.Is(err, errListItemExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Манга уже в списке"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при добавлении в список"})
+		return
+	}
+
+	item.Manga = &manga
+	c.JSON(http.StatusCreated, item)
+}
+
+func UpdateListItem(c *gin.Context) {
+	list, ok := loadOwnList(c)
+	if !ok {
+		return
+	}
+
+	mangaID, err := strconv.Atoi(c.Param("manga_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверное тело запроса"})
+		return
+	}
+
+	res := database.DB.Model(&models.ListItem{}).
+		Where("list_id = ? AND manga_id = ?", list.ID, mangaID).
+		Update("note", body.Note)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении заметки"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена в списке"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Заметка обновлена"})
+}
+
+func RemoveListItem(c *gin.Context) {
+	list, ok := loadOwnList(c)
+	if !ok {
+		return
+	}
+
+	mangaID, err := strconv.Atoi(c.Param("manga_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	// Удаляем элемент и сдвигаем позиции следующих за ним
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var item models.ListItem
+		if err := tx.Where("list_id = ? AND manga_id = ?", list.ID, mangaID).First(&item).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ListItem{}).
+			Where("list_id = ? AND position > ?", list.ID, item.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена в списке"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении из списка"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Манга удалена из списка"})
+}
+
+func ReorderListItems(c *gin.Context) {
+	list, ok := loadOwnList(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		MangaIDs []uint `json:"manga_ids"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверное тело запроса"})
+		return
+	}
+
+	// Новый порядок должен содержать ровно те же элементы, что и список
+	current := make(map[uint]bool, len(list.Items))
+	for _, item := range list.Items {
+		current[item.MangaID] = true
+	}
+	if len(body.MangaIDs) != len(current) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Порядок должен включать все элементы списка"})
+		return
+	}
+	for _, id := range body.MangaIDs {
+		if !current[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Порядок должен включать все элементы списка"})
+			return
+		}
+		delete(current, id)
+	}
+
+	// Видимые элементы получают позиции, которые они занимали, в новом порядке;
+	// скрытые остаются на своих местах
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var slots []int
+		err := tx.Model(&models.ListItem{}).
+			Where("list_id = ? AND manga_id IN ?", list.ID, body.MangaIDs).
+			Order("position ASC").
+			Pluck("position", &slots).Error
+		if err != nil {
+			return err
+		}
+		if len(slots) != len(body.MangaIDs) {
+			return gorm.ErrRecordNotFound
+		}
+		for i, id := range body.MangaIDs {
+			err := tx.Model(&models.ListItem{}).
+				Where("list_id = ? AND manga_id = ?", list.ID, id).
+				Update("position", slots[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(list).Update("updated_at", gorm.Expr("now()")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при изменении порядка"})
+		return
+	}
+
+	updated, err := loadList(database.DB, "id = ?", list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении списка"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func CopyList(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID списка"})
+		return
+	}
+
+	source, err := loadList(database.DB, "id = ?", listID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Список не найден"})
+		return
+	}
+
+	// Скрытый по ссылке список можно скопировать только зная токен
+	allowed := source.UserID == userID ||
+		source.Visibility == models.ListPublic ||
+		(source.Visibility == models.ListUnlisted && c.Query("token") == source.ShareToken)
+	if !allowed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Список не найден"})
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	_ = c.ShouldBindJSON(&body)
+	if body.Name == "" {
+		body.Name = source.Name
+	}
+
+	copied := models.List{
+		UserID:      userID,
+		Name:        body.Name,
+		Description: source.Description,
+		Visibility:  models.ListPrivate,
+		ShareToken:  uuid.New().String(),
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+		// Скрытая манга в копию не попадает, поэтому позиции нумеруем заново
+		for i, item := range source.Items {
+			newItem := models.ListItem{
+				ListID:   copied.ID,
+				MangaID:  item.MangaID,
+				Position: i + 1,
+				Note:     item.Note,
+			}
+			if err := tx.Create(&newItem).Error; err != nil {
+				return err
+			}
+			copied.Items = append(copied.Items, newItem)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при копировании списка"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, copied)
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"manga-catalog/database"
+	"manga-catalog/middleware"
+	"manga-catalog/models"
+	"manga-catalog/patch"
+	"manga-catalog/recommend"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// Редактируемое представление манги, к которому применяется патч
+type mangaDocument struct {
+	Title       string  `json:"title" binding:"required,max=255"`
+	Description string  `json:"description"`
+	Genre       string  `json:"genre" binding:"max=255"`
+	Status      string  `json:"status" binding:"required,oneof=draft scheduled published unlisted"`
+	PublishAt   *string `json:"publish_at"`
+}
+
+var mangaDocumentFields = map[string]bool{
+	"title": true, "description": true, "genre": true, "status": true, "publish_at": true,
+}
+
+func newMangaDocument(manga models.Manga) mangaDocument {
+	doc := mangaDocument{
+		Title:       manga.Title,
+		Description: manga.Description,
+		Genre:       manga.Genre,
+		Status:      manga.Status,
+	}
+	if manga.PublishAt != nil {
+		publishAt := manga.PublishAt.UTC().Format(time.RFC3339)
+		doc.PublishAt = &publishAt
+	}
+	return doc
+}
+
+// Переводит изменённый документ в изменения манги; поля, которые не поменялись, не трогаем
+func (doc mangaDocument) changes(current mangaDocument) (mangaChanges, fieldErrors) {
+	var ch mangaChanges
+	if doc.Title != current.Title {
+		ch.Title = &doc.Title
+	}
+	if doc.Description != current.Description {
+		ch.Description = &doc.Description
+	}
+	if doc.Genre != current.Genre {
+		ch.Genre = &doc.Genre
+	}
+
+	publishAtChanged := !equalStringPtr(doc.PublishAt, current.PublishAt)
+	if doc.Status == current.Status && !publishAtChanged {
+		return ch, nil
+	}
+	// Дату сбрасывает только перевод в черновик, молча игнорировать null нельзя
+	if doc.PublishAt == nil && current.PublishAt != nil && doc.Status != models.MangaDraft {
+		return ch, fieldErrors{"publish_at": "нельзя очистить для статуса " + doc.Status}
+	}
+
+	// publish_at проверяем, только если он важен для статуса или его меняют явно
+	publishAt := ""
+	if doc.PublishAt != nil && (doc.Status == models.MangaScheduled || publishAtChanged) {
+		publishAt = *doc.PublishAt
+	}
+	pub, fields := parsePublication(doc.Status, publishAt)
+	if fields != nil {
+		return ch, fields
+	}
+	ch.Publication = pub
+	return ch, nil
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Разбирает документ после патча: неизвестные поля и неверные значения возвращаются по полям
+func decodeMangaDocument(c *gin.Context, data []byte) (*mangaDocument, bool) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "После патча документ должен быть объектом"})
+		return nil, false
+	}
+	unknown := fieldErrors{}
+	for key := range raw {
+		if !mangaDocumentFields[key] {
+			unknown[key] = "неизвестное поле"
+		}
+	}
+	if len(unknown) > 0 {
+		respondValidation(c, unknown)
+		return nil, false
+	}
+
+	var doc mangaDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		respondBindError(c, err)
+		return nil, false
+	}
+	if err := binding.Validator.ValidateStruct(&doc); err != nil {
+		respondBindError(c, err)
+		return nil, false
+	}
+	return &doc, true
+}
+
+func PatchManga(c *gin.Context) {
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var apply func(doc, p []byte) ([]byte, error)
+	switch c.ContentType() {
+	case mergePatchType:
+		apply = patch.MergePatch
+	case jsonPatchType:
+		apply = patch.JSONPatch
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Поддерживаются " + mergePatchType + " и " + jsonPatchType})
+		return
+	}
+
+	var manga models.Manga
+	if err := database.DB.First(&manga, mangaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	if !canModify(c, manga.Authorship, middleware.PermMangaUpdateAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно редактировать только свою мангу"})
+		return
+	}
+
+	if !checkIfMatch(c, manga) {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil || len(body) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пустой патч"})
+		return
+	}
+
+	current := newMangaDocument(manga)
+	currentJSON, _ := json.Marshal(current)
+	patched, err := apply(currentJSON, body)
+	if errors.Is(err, patch.ErrInvalidPatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		// Патч корректен, но к текущему документу не применяется
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, ok := decodeMangaDocument(c, patched)
+	if !ok {
+		return
+	}
+	changes, fields := doc.changes(current)
+	if fields != nil {
+		respondValidation(c, fields)
+		return
+	}
+
+	var similarChanged bool
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		similarChanged, err = updateManga(tx, c, &manga, changes, nil)
+		return err
+	})
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, manga)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении манги"})
+		return
+	}
+
+	if similarChanged {
+		recommend.RefreshSimilar(manga.ID)
+	}
+
+	c.Header("ETag", mangaETag(manga))
+	c.JSON(http.StatusOK, manga)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"manga-catalog/audit"
+	"manga-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Собирает новую мангу из проверенного запроса; без status она публикуется сразу, как и раньше
+func (req mangaCreateRequest) manga(userID uint) (models.Manga, fieldErrors) {
+	pub, fields := parsePublication(req.Status, req.PublishAt)
+	if fields != nil {
+		return models.Manga{}, fields
+	}
+	if pub == nil {
+		pub = &publication{Status: models.MangaPublished}
+	}
+
+	manga := models.Manga{
+		Title:       req.Title,
+		Description: req.Description,
+		Genre:       req.Genre,
+	}
+	pub.apply(&manga)
+	manga.SetCreator(userID)
+	return manga, nil
+}
+
+// Единый путь создания манги: запись, первая ревизия и аудит
+func insertManga(tx *gorm.DB, c *gin.Context, manga *models.Manga) error {
+	if err := tx.Create(manga).Error; err != nil {
+		return err
+	}
+	if err := audit.WriteRevision(tx, manga, c.GetUint("user_id"), nil); err != nil {
+		return err
+	}
+	return audit.Record(tx, c, audit.Create, "manga", manga.ID, nil, manga)
+}
+
+// Переносит мангу в корзину, только если её версия не изменилась с момента чтения
+func deleteManga(tx *gorm.DB, c *gin.Context, manga *models.Manga) error {
+	result := tx.Where("version = ?", manga.Version).Delete(manga)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	return audit.Record(tx, c, audit.Delete, "manga", manga.ID, manga, nil)
+}
+
+// Изменения редактируемых полей манги; nil — поле не трогаем
+type mangaChanges struct {
+	Title       *string
+	Description *string
+	Genre       *string
+	Publication *publication
+}
+
+// Тело PUT: непереданные поля не меняются, null или пустое значение очищает поле
+type mangaUpdateRequest struct {
+	Title       optionalString `json:"title" form:"title" binding:"omitempty,max=255"`
+	Description optionalString `json:"description" form:"description"`
+	Genre       optionalString `json:"genre" form:"genre" binding:"omitempty,max=255"`
+	Status      string         `json:"status" form:"status"`
+	PublishAt   string         `json:"publish_at" form:"publish_at"`
+}
+
+func (req mangaUpdateRequest) changes() (mangaChanges, fieldErrors) {
+	// Без названия манга не найдётся ни в каталоге, ни в поиске
+	if req.Title.cleared() {
+		return mangaChanges{}, fieldErrors{"title": "название нельзя очистить"}
+	}
+
+	pub, fields := parsePublication(req.Status, req.PublishAt)
+	if fields != nil {
+		return mangaChanges{}, fields
+	}
+
+	return mangaChanges{
+		Title:       req.Title.ptr(),
+		Description: req.Description.ptr(),
+		Genre:       req.Genre.ptr(),
+		Publication: pub,
+	}, nil
+}
+
+func (ch mangaChanges) apply(manga *models.Manga) {
+	if ch.Title != nil {
+		manga.Title = *ch.Title
+	}
+	if ch.Description != nil {
+		manga.Description = *ch.Description
+	}
+	if ch.Genre != nil {
+		manga.Genre = *ch.Genre
+	}
+	if ch.Publication != nil {
+		ch.Publication.apply(manga)
+	}
+}
+
+func sameEditableFields(a, b models.Manga) bool {
+	samePublishAt := a.PublishAt == nil && b.PublishAt == nil ||
+		a.PublishAt != nil && b.PublishAt != nil && a.PublishAt.Equal(*b.PublishAt)
+	return a.Title == b.Title && a.Description == b.Description && a.Genre == b.Genre &&
+		a.Status == b.Status && samePublishAt
+}
+
+// Единый путь изменения манги: сохраняет поля, пишет ревизию и аудит.
+// Если поля не изменились, ничего не записывает.
+// Возвращает true, если изменились поля, влияющие на похожие тайтлы.
+func updateManga(tx *gorm.DB, c *gin.Context, manga *models.Manga, changes mangaChanges, rollbackOf *int) (bool, error) {
+	// Блокируем строку, чтобы номера ревизий и состояние "до" не перепутались при параллельных правках.
+	// manga — версия, которую видел клиент; если с тех пор её изменили, правку не применяем
+	var current models.Manga
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, manga.ID).Error; err != nil {
+		return false, err
+	}
+	if current.Version != manga.Version {
+		*manga = current
+		return false, errVersionConflict
+	}
+	*manga = current
+
+	before := *manga
+	changes.apply(manga)
+	// Пустая правка не должна менять версию: иначе у всех остальных клиентов устареет ETag
+	if sameEditableFields(before, *manga) {
+		return false, nil
+	}
+
+	userID := c.GetUint("user_id")
+	manga.SetUpdater(userID)
+	manga.Version++
+
+	// Счётчики обновляются отдельно, поэтому сохраняем только редактируемые поля
+	if err := tx.Model(manga).Select("title", "description", "genre", "status", "publish_at", "updated_by", "version").Updates(manga).Error; err != nil {
+		return false, err
+	}
+	if err := audit.WriteRevision(tx, manga, userID, rollbackOf); err != nil {
+		return false, err
+	}
+
+	action := audit.Update
+	if rollbackOf != nil {
+		action = audit.Rollback
+	}
+	if err := audit.Record(tx, c, action, "manga", manga.ID, before, manga); err != nil {
+		return false, err
+	}
+
+	return manga.Genre != before.Genre || manga.Description != before.Description, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Создаёт уведомление в той же транзакции, что и событие, о котором оно сообщает
+func notify(tx *gorm.DB, userID uint, kind, message, entityType string, entityID uint) error {
+	return tx.Create(&models.Notification{
+		UserID:     userID,
+		Type:       kind,
+		Message:    message,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}).Error
+}
+
+func GetNotifications(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	limit, page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте total"})
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении уведомлений"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  notifications,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+func MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID уведомления"})
+		return
+	}
+
+	var notification models.Notification
+	if err := database.DB.Where("user_id = ?", c.GetUint("user_id")).First(&notification, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Уведомление не найдено"})
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := database.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении уведомления"})
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/middleware"
+	"manga-catalog/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Запрошенное состояние публикации; PublishAt задаётся только для scheduled
+type publication struct {
+	Status    string
+	PublishAt *time.Time
+}
+
+// Разбирает status и publish_at из запроса; publish_at без status означает отложенную публикацию.
+// Возвращает nil, если ни одно поле не передано
+func parsePublication(status, publishAt string) (*publication, fieldErrors) {
+	if status == "" && publishAt == "" {
+		return nil, nil
+	}
+
+	p := &publication{Status: status}
+	if publishAt != "" {
+		t, err := time.Parse(time.RFC3339, publishAt)
+		if err != nil {
+			return nil, fieldErrors{"publish_at": "ожидается дата в формате RFC3339"}
+		}
+		p.PublishAt = &t
+		if p.Status == "" {
+			p.Status = models.MangaScheduled
+		}
+	}
+
+	if !models.ValidMangaStatus(p.Status) {
+		return nil, fieldErrors{"status": "неверный статус публикации"}
+	}
+	if p.Status == models.MangaScheduled {
+		if p.PublishAt == nil || !p.PublishAt.After(time.Now()) {
+			return nil, fieldErrors{"publish_at": "для отложенной публикации нужна дата в будущем"}
+		}
+	} else if p.PublishAt != nil {
+		return nil, fieldErrors{"publish_at": "задаётся только для статуса scheduled"}
+	}
+	return p, nil
+}
+
+func (p publication) apply(manga *models.Manga) {
+	manga.Status = p.Status
+	switch p.Status {
+	case models.MangaScheduled:
+		manga.PublishAt = p.PublishAt
+	case models.MangaDraft:
+		manga.PublishAt = nil
+	default:
+		// Время публикации фиксируется, когда манга впервые стала доступна
+		if manga.PublishAt == nil || manga.PublishAt.After(time.Now()) {
+			now := time.Now()
+			manga.PublishAt = &now
+		}
+	}
+}
+
+// Неопубликованную мангу видят только автор и модераторы
+func canSee(c *gin.Context, manga models.Manga) bool {
+	return manga.IsVisible() || canModify(c, manga.Authorship, middleware.PermMangaUpdateAny)
+}
+
+// Как First, но скрытая от пользователя манга считается ненайденной
+func findVisibleManga(c *gin.Context, manga *models.Manga, id interface{}) error {
+	if err := database.DB.First(manga, id).Error; err != nil {
+		return err
+	}
+	if !canSee(c, *manga) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// То же, что canSee, но условием запроса: чужие черновики и запланированные видят только модераторы
+func visibleManga(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if middleware.HasPermission(c, middleware.PermMangaUpdateAny) {
+			return db
+		}
+		return db.Where("mangas.status IN ? OR mangas.created_by = ?",
+			[]string{models.MangaPublished, models.MangaUnlisted}, c.GetUint("user_id"))
+	}
+}
+
+// В каталоги, рейтинги и подборки попадает только опубликованная манга
+func listedManga(db *gorm.DB) *gorm.DB {
+	return db.Where("mangas.status = ?", models.MangaPublished)
+}
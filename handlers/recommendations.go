@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"manga-catalog/recommend"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type recommendation struct {
+	models.Manga
+	Score float64 `json:"score"`
+}
+
+func GetRecommendations(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный параметр limit"})
+		return
+	}
+
+	var library []uint
+	if err := database.DB.Model(&models.Favorite{}).Where("user_id = ?", userID).Pluck("manga_id", &library).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении избранного"})
+		return
+	}
+
+	scored := recommend.ForLibrary(library, limit)
+	ids := make([]uint, len(scored))
+	for i, s := range scored {
+		ids[i] = s.ID
+	}
+
+	var manga []models.Manga
+	if len(ids) > 0 {
+		if err := database.DB.Scopes(listedManga).Where("id IN ?", ids).Find(&manga).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка базы данных"})
+			return
+		}
+	}
+
+	byID := make(map[uint]models.Manga, len(manga))
+	for _, m := range manga {
+		byID[m.ID] = m
+	}
+
+	// Сохраняем порядок модели, пропуская удалённые и неопубликованные тайтлы
+	result := []recommendation{}
+	for _, s := range scored {
+		if m, ok := byID[s.ID]; ok {
+			result = append(result, recommendation{Manga: m, Score: s.Score})
+		}
+	}
+
+	// Без истории рекомендовать нечего, показываем популярное
+	if len(result) == 0 {
+		query := database.DB.Scopes(listedManga).Order("favorites_count DESC").Order("id").Limit(limit)
+		if len(library) > 0 {
+			query = query.Where("id NOT IN ?", library)
+		}
+		if err := query.Find(&manga).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка базы данных"})
+			return
+		}
+		for _, m := range manga {
+			result = append(result, recommendation{Manga: m})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"manga-catalog/audit"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"manga-catalog/recommend"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func GetMangaRevisions(c *gin.Context) {
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	limit, page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.MangaRevision{}).Where("manga_id = ?", manga.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте total"})
+		return
+	}
+
+	var revisions []models.MangaRevision
+	if err := query.Order("number DESC").Limit(limit).Offset((page - 1) * limit).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении ревизий"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  revisions,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+func findRevision(mangaID uint, number string) (*models.MangaRevision, error) {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var revision models.MangaRevision
+	if err := database.DB.Where("manga_id = ? AND number = ?", mangaID, n).First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Разница по полям между ревизиями from и to
+func GetMangaRevisionDiff(c *gin.Context) {
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	if c.Query("from") == "" || c.Query("to") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нужно указать from и to"})
+		return
+	}
+
+	from, err := findRevision(manga.ID, c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ревизия from не найдена"})
+		return
+	}
+	to, err := findRevision(manga.ID, c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ревизия to не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from.Number,
+		"to":   to.Number,
+		"diff": audit.Diff(from.Fields(), to.Fields()),
+	})
+}
+
+// Возвращает поля манги к выбранной ревизии; откат сам становится новой ревизией
+func RollbackManga(c *gin.Context) {
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var manga models.Manga
+	if err := database.DB.First(&manga, mangaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	revision, err := findRevision(manga.ID, c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ревизия не найдена"})
+		return
+	}
+
+	// If-Match для отката не обязателен, но если передан — проверяем
+	if c.GetHeader("If-Match") != "" && !checkIfMatch(c, manga) {
+		return
+	}
+
+	changes := mangaChanges{
+		Title:       &revision.Title,
+		Description: &revision.Description,
+		Genre:       &revision.Genre,
+	}
+
+	var similarChanged bool
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		similarChanged, err = updateManga(tx, c, &manga, changes, &revision.Number)
+		return err
+	})
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, manga)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при откате манги"})
+		return
+	}
+
+	if similarChanged {
+		recommend.RefreshSimilar(manga.ID)
+	}
+
+	c.Header("ETag", mangaETag(manga))
+	c.JSON(http.StatusOK, manga)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"manga-catalog/audit"
+	"manga-catalog/database"
+	"manga-catalog/middleware"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Если срок жизни токена неизвестен, храним запись об отзыве с запасом
+const defaultRevocationTTL = 30 * 24 * time.Hour
+
+func RevokeToken(c *gin.Context) {
+	var body struct {
+		JTI       string     `json:"jti"`
+		UserID    uint       `json:"user_id"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		Reason    string     `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.JTI == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "jti обязателен"})
+		return
+	}
+
+	expiresAt := time.Now().Add(defaultRevocationTTL)
+	if body.ExpiresAt != nil {
+		expiresAt = *body.ExpiresAt
+	}
+
+	revoked := models.RevokedToken{
+		JTI:       body.JTI,
+		UserID:    body.UserID,
+		ExpiresAt: expiresAt,
+		Reason:    body.Reason,
+		RevokedBy: c.GetUint("user_id"),
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := audit.Record(tx, c, audit.Revoke, "token", revoked.UserID, nil, revoked); err != nil {
+			return err
+		}
+		return middleware.RevokeToken(tx, &revoked)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при отзыве токена"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, revoked)
+}
+
+func RevokeUserTokens(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&body)
+
+	revocation := models.UserTokenRevocation{
+		UserID:        uint(userID),
+		RevokedBefore: time.Now().Truncate(time.Second),
+		Reason:        body.Reason,
+		RevokedBy:     c.GetUint("user_id"),
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := audit.Record(tx, c, audit.Revoke, "user_tokens", revocation.UserID, nil, revocation); err != nil {
+			return err
+		}
+		return middleware.RevokeUserTokens(tx, &revocation)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при отзыве токенов"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, revocation)
+}
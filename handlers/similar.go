@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type similarRow struct {
+	models.Manga `gorm:"embedded"`
+	Score        float64 `json:"score"`
+}
+
+func GetSimilarManga(c *gin.Context) {
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 || limit > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный параметр limit"})
+		return
+	}
+
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	rows := []similarRow{}
+	err = database.DB.
+		Model(&models.Manga{}).
+		Select("mangas.*, manga_similars.score").
+		Joins("JOIN manga_similars ON manga_similars.similar_id = mangas.id").
+		Where("manga_similars.manga_id = ?", mangaID).
+		Scopes(listedManga).
+		Order("manga_similars.score DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении похожих тайтлов"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rows)
+}
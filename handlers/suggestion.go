@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"manga-catalog/audit"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"manga-catalog/recommend"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errSuggestionReviewed = errors.New("предложение уже рассмотрено")
+	errMangaGone          = errors.New("манга удалена")
+)
+
+func CreateSuggestion(c *gin.Context) {
+	mangaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var manga models.Manga
+	if err := findVisibleManga(c, &manga, mangaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+
+	var body struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		Genre       *string `json:"genre"`
+		Comment     string  `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса"})
+		return
+	}
+
+	// Оставляем только поля, которые действительно отличаются от текущих
+	suggestion := models.MangaSuggestion{
+		MangaID:     manga.ID,
+		UserID:      c.GetUint("user_id"),
+		Comment:     strings.TrimSpace(body.Comment),
+		Status:      models.SuggestionPending,
+		BaseVersion: manga.Version,
+	}
+	fields := []struct {
+		proposed *string
+		current  string
+		target   **string
+	}{
+		{body.Title, manga.Title, &suggestion.Title},
+		{body.Description, manga.Description, &suggestion.Description},
+		{body.Genre, manga.Genre, &suggestion.Genre},
+	}
+	changed := false
+	for _, f := range fields {
+		if f.proposed == nil {
+			continue
+		}
+		value := strings.TrimSpace(*f.proposed)
+		if value == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Предлагаемые поля не могут быть пустыми"})
+			return
+		}
+		if value != f.current {
+			*f.target = &value
+			changed = true
+		}
+	}
+	if !changed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Предложение ничего не меняет"})
+		return
+	}
+
+	// Второе ожидающее предложение того же пользователя отсекает частичный уникальный индекс
+	res := database.DB.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "manga_id"}, {Name: "user_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "status", Value: models.SuggestionPending}}},
+		DoNothing:   true,
+	}).Create(&suggestion)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании предложения"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ваше предложение к этой манге уже ожидает проверки"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, suggestion)
+}
+
+func listSuggestions(c *gin.Context, query *gorm.DB, order string) {
+	limit, page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте total"})
+		return
+	}
+
+	var suggestions []models.MangaSuggestion
+	if err := query.Order(order).Limit(limit).Offset((page - 1) * limit).Find(&suggestions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении предложений"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  suggestions,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+// Предложения текущего пользователя
+func GetMySuggestions(c *gin.Context) {
+	query := database.DB.Model(&models.MangaSuggestion{}).Where("user_id = ?", c.GetUint("user_id"))
+	listSuggestions(c, query, "created_at DESC, id DESC")
+}
+
+// Очередь модерации: по умолчанию ожидающие, старые сначала
+func GetSuggestionQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.SuggestionPending)
+	switch status {
+	case models.SuggestionPending, models.SuggestionApproved, models.SuggestionRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный статус"})
+		return
+	}
+
+	query := database.DB.Model(&models.MangaSuggestion{}).Where("status = ?", status)
+	if mangaID := c.Query("manga_id"); mangaID != "" {
+		query = query.Where("manga_id = ?", mangaID)
+	}
+	listSuggestions(c, query, "created_at ASC, id ASC")
+}
+
+// Блокирует предложение и проверяет, что его ещё никто не рассмотрел
+func lockPendingSuggestion(tx *gorm.DB, id uint) (*models.MangaSuggestion, error) {
+	var suggestion models.MangaSuggestion
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&suggestion, id).Error; err != nil {
+		return nil, err
+	}
+	if suggestion.Status != models.SuggestionPending {
+		return nil, errSuggestionReviewed
+	}
+	return &suggestion, nil
+}
+
+func markReviewed(tx *gorm.DB, c *gin.Context, suggestion *models.MangaSuggestion, status, reason string) error {
+	before := *suggestion
+	reviewerID := c.GetUint("user_id")
+	now := time.Now()
+
+	suggestion.Status = status
+	suggestion.Reason = reason
+	suggestion.ReviewerID = &reviewerID
+	suggestion.ReviewedAt = &now
+	if err := tx.Model(suggestion).Select("status", "reason", "reviewer_id", "reviewed_at").Updates(suggestion).Error; err != nil {
+		return err
+	}
+
+	action := audit.Approve
+	if status == models.SuggestionRejected {
+		action = audit.Reject
+	}
+	return audit.Record(tx, c, action, "suggestion", suggestion.ID, before, suggestion)
+}
+
+func parseSuggestionID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID предложения"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondReviewError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Предложение не найдено"})
+	case errors.Is(err, errSuggestionReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Предложение уже рассмотрено"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// Применяет предложение тем же путём, что и UpdateManga, и уведомляет автора
+func ApproveSuggestion(c *gin.Context) {
+	id, ok := parseSuggestionID(c)
+	if !ok {
+		return
+	}
+
+	var manga models.Manga
+	var similarChanged bool
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		suggestion, err := lockPendingSuggestion(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&manga, suggestion.MangaID).Error; err != nil {
+			return errMangaGone
+		}
+		// Манга менялась после предложения: применение затёрло бы более поздние правки
+		if manga.Version != suggestion.BaseVersion {
+			return errVersionConflict
+		}
+
+		changes := mangaChanges{
+			Title:       suggestion.Title,
+			Description: suggestion.Description,
+			Genre:       suggestion.Genre,
+		}
+		if similarChanged, err = updateManga(tx, c, &manga, changes, nil); err != nil {
+			return err
+		}
+		if err := markReviewed(tx, c, suggestion, models.SuggestionApproved, ""); err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("Ваше предложение к «%s» принято", manga.Title)
+		return notify(tx, suggestion.UserID, "suggestion_approved", message, "suggestion", suggestion.ID)
+	})
+	if errors.Is(err, errMangaGone) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена"})
+		return
+	}
+	if errors.Is(err, errVersionConflict) {
+		c.Header("ETag", mangaETag(manga))
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Манга изменилась после предложения, отклоните его или попросите прислать заново",
+			"manga": manga,
+		})
+		return
+	}
+	if err != nil {
+		respondReviewError(c, err, "Ошибка при применении предложения")
+		return
+	}
+
+	if similarChanged {
+		recommend.RefreshSimilar(manga.ID)
+	}
+
+	c.Header("ETag", mangaETag(manga))
+	c.JSON(http.StatusOK, manga)
+}
+
+func RejectSuggestion(c *gin.Context) {
+	id, ok := parseSuggestionID(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || strings.TrimSpace(body.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите причину отклонения"})
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+
+	var suggestion *models.MangaSuggestion
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if suggestion, err = lockPendingSuggestion(tx, id); err != nil {
+			return err
+		}
+		if err := markReviewed(tx, c, suggestion, models.SuggestionRejected, reason); err != nil {
+			return err
+		}
+
+		// Название берём и у манги из корзины, чтобы автору было понятно, о чём речь
+		var manga models.Manga
+		tx.Unscoped().Select("title").First(&manga, suggestion.MangaID)
+		message := fmt.Sprintf("Ваше предложение к «%s» отклонено: %s", manga.Title, reason)
+		return notify(tx, suggestion.UserID, "suggestion_rejected", message, "suggestion", suggestion.ID)
+	})
+	if err != nil {
+		respondReviewError(c, err, "Ошибка при отклонении предложения")
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestion)
+}
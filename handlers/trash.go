@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"manga-catalog/audit"
+	"manga-catalog/database"
+	"manga-catalog/jobs"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type trashItem struct {
+	models.Manga
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+func GetTrash(c *gin.Context) {
+	limit, page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Unscoped().Model(&models.Manga{}).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте total"})
+		return
+	}
+
+	var manga []models.Manga
+	if err := query.Order("deleted_at DESC").Limit(limit).Offset((page - 1) * limit).Find(&manga).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении корзины"})
+		return
+	}
+
+	retention := jobs.TrashRetention()
+	items := make([]trashItem, len(manga))
+	for i, m := range manga {
+		items[i] = trashItem{
+			Manga:     m,
+			DeletedAt: m.DeletedAt.Time,
+			PurgeAt:   m.DeletedAt.Time.Add(retention),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  items,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+func RestoreManga(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID манги"})
+		return
+	}
+
+	var manga models.Manga
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&manga, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Манга не найдена в корзине"})
+		return
+	}
+
+	before := manga
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&manga).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, audit.Restore, "manga", manga.ID, trashItem{Manga: before, DeletedAt: before.DeletedAt.Time}, manga)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при восстановлении манги"})
+		return
+	}
+
+	c.JSON(http.StatusOK, manga)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"manga-catalog/activity"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type trendingRow struct {
+	models.Manga `gorm:"embedded"`
+	Score        float64   `json:"score"`
+	ComputedAt   time.Time `json:"-"`
+}
+
+func GetTrending(c *gin.Context) {
+	window, ok := activity.FindWindow(c.DefaultQuery("window", "week"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверное окно: допустимы day, week, month, all"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный параметр limit"})
+		return
+	}
+
+	// Рейтинг уже посчитан агрегатором, здесь только чтение
+	rows := []trendingRow{}
+	err = database.DB.
+		Model(&models.Manga{}).
+		Select("mangas.*, manga_trendings.score, manga_trendings.computed_at").
+		Joins("JOIN manga_trendings ON manga_trendings.manga_id = mangas.id").
+		Where("manga_trendings.time_window = ?", window.Name).
+		Scopes(listedManga).
+		Order("manga_trendings.score DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении рейтинга"})
+		return
+	}
+
+	var computedAt *time.Time
+	if len(rows) > 0 {
+		computedAt = &rows[0].ComputedAt
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":      window.Name,
+		"computed_at": computedAt,
+		"data":        rows,
+	})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/models"
+)
+
+// Состояние манги для текущего пользователя; для анонимов не отдаётся
+type mangaViewer struct {
+	InLibrary bool   `json:"in_library"`
+	Lists     []uint `json:"lists"`
+}
+
+type mangaResponse struct {
+	models.Manga
+	Viewer *mangaViewer `json:"viewer,omitempty"`
+}
+
+type commentResponse struct {
+	models.Comment
+	IsOwn *bool `json:"is_own,omitempty"`
+}
+
+// Одним запросом на таблицу собирает состояние пользователя для набора манги
+func mangaViewers(userID uint, ids []uint) (map[uint]*mangaViewer, error) {
+	viewers := make(map[uint]*mangaViewer, len(ids))
+	if userID == 0 || len(ids) == 0 {
+		return viewers, nil
+	}
+	for _, id := range ids {
+		viewers[id] = &mangaViewer{Lists: []uint{}}
+	}
+
+	var favorites []uint
+	err := database.DB.Model(&models.Favorite{}).
+		Where("user_id = ? AND manga_id IN ?", userID, ids).
+		Pluck("manga_id", &favorites).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range favorites {
+		viewers[id].InLibrary = true
+	}
+
+	var items []models.ListItem
+	err = database.DB.
+		Select("list_items.list_id", "list_items.manga_id").
+		Joins("JOIN lists ON lists.id = list_items.list_id").
+		Where("lists.user_id = ? AND list_items.manga_id IN ?", userID, ids).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		viewers[item.MangaID].Lists = append(viewers[item.MangaID].Lists, item.ListID)
+	}
+
+	return viewers, nil
+}
+
+func withViewers(userID uint, manga []models.Manga) ([]mangaResponse, error) {
+	ids := make([]uint, len(manga))
+	for i, m := range manga {
+		ids[i] = m.ID
+	}
+
+	viewers, err := mangaViewers(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]mangaResponse, len(manga))
+	for i, m := range manga {
+		result[i] = mangaResponse{Manga: m, Viewer: viewers[m.ID]}
+	}
+	return result, nil
+}
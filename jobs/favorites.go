@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"log"
+	"manga-catalog/database"
+)
+
+// Пересчитывает favorites_count по таблице favorites и чинит расхождения
+func ReconcileFavoritesCount() error {
+	res := database.DB.Exec(`
+		UPDATE mangas m
+		SET favorites_count = f.cnt
+		FROM (
+			SELECT mangas.id, COUNT(favorites.id) AS cnt
+			FROM mangas
+			LEFT JOIN favorites ON favorites.manga_id = mangas.id
+			GROUP BY mangas.id
+		) f
+		WHERE m.id = f.id AND m.favorites_count <> f.cnt`)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected > 0 {
+		log.Printf("Исправлен favorites_count у %d манги", res.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"log"
+	"manga-catalog/audit"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Публикует мангу, у которой наступило запланированное время
+func PublishScheduled() error {
+	var due []models.Manga
+	err := database.DB.
+		Where("status = ? AND publish_at <= ?", models.MangaScheduled, time.Now()).
+		Find(&due).Error
+	if err != nil {
+		return err
+	}
+
+	published := 0
+	for _, manga := range due {
+		before := manga
+		manga.Status = models.MangaPublished
+		manga.Version++
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// Пока задача шла, автор мог перенести публикацию — тогда версия уже другая
+			res := tx.Model(&manga).
+				Where("status = ? AND version = ?", models.MangaScheduled, before.Version).
+				Select("status", "version").
+				Updates(&manga)
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			published++
+			if err := audit.WriteRevision(tx, &manga, 0, nil); err != nil {
+				return err
+			}
+			return audit.RecordSystem(tx, audit.Publish, "manga", manga.ID, before, manga)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if published > 0 {
+		log.Printf("Опубликовано по расписанию: %d манги", published)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Запускает fn сразу и затем с заданным интервалом, ошибки только логируются
+func Every(name string, interval time.Duration, fn func() error) {
+	every(name, interval, fn, false)
+}
+
+// Как Every, но без записи в лог об успешных запусках — для частых задач
+func EveryQuiet(name string, interval time.Duration, fn func() error) {
+	every(name, interval, fn, true)
+}
+
+func every(name string, interval time.Duration, fn func() error, quiet bool) {
+	run(name, fn, quiet)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		run(name, fn, quiet)
+	}
+}
+
+// Запускает fn раз в сутки в указанный час по UTC
+func Daily(name string, hour int, fn func() error) {
+	for {
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.Add(24 * time.Hour)
+		}
+		time.Sleep(time.Until(next))
+		run(name, fn, false)
+	}
+}
+
+func run(name string, fn func() error, quiet bool) {
+	start := time.Now()
+	if err := fn(); err != nil {
+		log.Printf("[job %s] ошибка: %v", name, err)
+		return
+	}
+	if !quiet {
+		log.Printf("[job %s] выполнено за %s", name, time.Since(start).Round(time.Millisecond))
+	}
+}
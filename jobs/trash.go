@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"database/sql"
+	"log"
+	"manga-catalog/audit"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Сколько манга лежит в корзине до окончательного удаления, TRASH_RETENTION (например, 720h)
+func TrashRetention() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("TRASH_RETENTION")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * 24 * time.Hour
+}
+
+// Таблицы, где строки ссылаются на мангу и должны уйти вместе с ней
+var mangaDependents = []string{
+	"DELETE FROM comments WHERE manga_id = @id",
+	"DELETE FROM favorites WHERE manga_id = @id",
+	"DELETE FROM list_items WHERE manga_id = @id",
+	"DELETE FROM manga_activities WHERE manga_id = @id",
+	"DELETE FROM manga_trendings WHERE manga_id = @id",
+	"DELETE FROM manga_similars WHERE manga_id = @id OR similar_id = @id",
+	"DELETE FROM manga_revisions WHERE manga_id = @id",
+	"DELETE FROM manga_suggestions WHERE manga_id = @id",
+}
+
+// Окончательно удаляет мангу, пролежавшую в корзине дольше срока хранения
+func PurgeTrash() error {
+	var expired []models.Manga
+	err := database.DB.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-TrashRetention())).
+		Find(&expired).Error
+	if err != nil {
+		return err
+	}
+
+	for _, manga := range expired {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			for _, query := range mangaDependents {
+				if err := tx.Exec(query, sql.Named("id", manga.ID)).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Unscoped().Delete(&manga).Error; err != nil {
+				return err
+			}
+			return audit.RecordSystem(tx, audit.Purge, "manga", manga.ID, manga, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(expired) > 0 {
+		log.Printf("Из корзины окончательно удалено %d манги", len(expired))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"fmt"
+	"manga-catalog/activity"
+	"manga-catalog/database"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CASE-выражение с весами событий, порядок стабилен для читаемости логов
+func weightExpr() string {
+	kinds := make([]string, 0, len(activity.Weights))
+	for kind := range activity.Weights {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var b strings.Builder
+	b.WriteString("CASE kind")
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %g", kind, activity.Weights[kind])
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}
+
+// Пересчитывает рейтинги для всех окон с экспоненциальным затуханием по возрасту события
+func AggregateTrending() error {
+	weight := weightExpr()
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, w := range activity.Windows {
+			decay := "1"
+			if w.HalfLife > 0 {
+				decay = fmt.Sprintf("EXP(-LN(2) * EXTRACT(EPOCH FROM (now() - bucket)) / %f)", w.HalfLife.Seconds())
+			}
+			where := "TRUE"
+			if w.Range > 0 {
+				where = fmt.Sprintf("bucket >= now() - make_interval(secs => %f)", w.Range.Seconds())
+			}
+
+			if err := tx.Exec("DELETE FROM manga_trendings WHERE time_window = ?", w.Name).Error; err != nil {
+				return err
+			}
+
+			err := tx.Exec(fmt.Sprintf(`
+				INSERT INTO manga_trendings (time_window, manga_id, score, computed_at)
+				SELECT ?, manga_id, SUM((%s) * count * %s), now()
+				FROM manga_activities
+				WHERE %s
+				GROUP BY manga_id`, weight, decay, where), w.Name).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
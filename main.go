@@ -2,47 +2,151 @@ package main
 
 import (
 	"github.com/gin-contrib/cors"
+	"log"
 	"manga-catalog/database"
 	"manga-catalog/handlers"
+	"manga-catalog/jobs"
 	"manga-catalog/middleware"
+	"manga-catalog/recommend"
+	"manga-catalog/views"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Лимиты запросов по группам маршрутов
+var (
+	readLimit    = middleware.RatePolicy{Name: "reads", Limit: 120, Per: time.Minute}
+	writeLimit   = middleware.RatePolicy{Name: "writes", Limit: 60, Per: time.Minute}
+	commentLimit = middleware.RatePolicy{Name: "comments", Limit: 5, Per: time.Minute}
+	// Неудачные попытки входа с одного IP
+	authFailureLimit = middleware.RatePolicy{Name: "auth-failures", Limit: 10, Per: time.Minute}
+)
+
+// TRUSTED_PROXIES — адреса или подсети прокси через запятую: только от них принимается X-Forwarded-For.
+// TRUSTED_PLATFORM — заголовок, в котором платформа передаёт IP клиента (например, CF-Connecting-IP).
+// По умолчанию не доверяем никому, и IP клиента — адрес соединения
+func configureProxies(r *gin.Engine) error {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		return err
+	}
+	r.TrustedPlatform = os.Getenv("TRUSTED_PLATFORM")
+	return nil
+}
+
 func main() {
 	database.ConnectDB()
+	if err := middleware.LoadAuthConfig(); err != nil {
+		log.Fatal("Ошибка настройки JWT:", err)
+	}
+	if err := middleware.LoadRateLimitConfig(); err != nil {
+		log.Fatal("Ошибка настройки rate limiter:", err)
+	}
+	if err := middleware.LoadIdempotencyConfig(); err != nil {
+		log.Fatal("Ошибка настройки ключей идемпотентности:", err)
+	}
+	// Без списка отзыва после рестарта принимались бы отозванные токены
+	if err := middleware.ReloadRevocations(); err != nil {
+		log.Fatal("Ошибка загрузки списка отозванных токенов:", err)
+	}
+
+	go jobs.Every("jwks", 10*time.Minute, middleware.RefreshJWKS)
+	go jobs.EveryQuiet("revocations", 30*time.Second, middleware.ReloadRevocations)
+	go jobs.Every("rate-limit-cleanup", time.Hour, middleware.CleanupRateLimits)
+	go jobs.Every("idempotency-cleanup", time.Hour, middleware.CleanupIdempotencyKeys)
+	go jobs.Daily("favorites-reconcile", 3, jobs.ReconcileFavoritesCount)
+	go jobs.Daily("trash-purge", 4, jobs.PurgeTrash)
+	go jobs.Every("trending", 5*time.Minute, jobs.AggregateTrending)
+	go jobs.EveryQuiet("publish-scheduled", time.Minute, jobs.PublishScheduled)
+	go jobs.EveryQuiet("views-flush", 10*time.Second, views.Flush)
+	go jobs.Every("similar", 6*time.Hour, recommend.RebuildSimilar)
+	go recommend.RunSimilarWorker()
+	go jobs.Every("recommendations", time.Hour, recommend.RebuildCollaborative)
 
 	r := gin.New()
+	if err := configureProxies(r); err != nil {
+		log.Fatal("Ошибка настройки доверенных прокси:", err)
+	}
 	r.Use(gin.Recovery())
 	r.Use(middleware.LoggingMiddleware())
 	r.Static("/uploads", "./uploads")
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "If-Match", "If-None-Match", "Idempotency-Key"},
+		ExposeHeaders:    []string{"ETag", "X-Request-ID", "Retry-After", "Idempotent-Replayed", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
 		AllowCredentials: true,
 	}))
 
 	api := r.Group("/api")
+	api.Use(middleware.AuthFailureLimit(authFailureLimit), middleware.OptionalAuthMiddleware(), middleware.RateLimit(readLimit))
 	{
 		api.GET("/manga", handlers.GetMangaList)
+		api.GET("/manga/trending", handlers.GetTrending)
 		api.GET("/manga/:id", handlers.GetMangaByID)
 		api.GET("/genres", handlers.GetAllGenres)
 		api.GET("/genres/stats", handlers.GetGenresWithCount)
 		api.GET("/manga/:id/comments", handlers.GetComments)
+		api.GET("/manga/:id/favorites/stats", handlers.GetFavoriteStats)
+		api.GET("/manga/:id/similar", handlers.GetSimilarManga)
+		api.GET("/manga/:id/revisions", handlers.GetMangaRevisions)
+		api.GET("/manga/:id/revisions/diff", handlers.GetMangaRevisionDiff)
+		api.GET("/users/:id/lists", handlers.GetUserPublicLists)
+		api.GET("/lists/shared/:token", handlers.GetSharedList)
+		api.GET("/lists/:id", handlers.GetList)
 	}
 
 	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthFailureLimit(authFailureLimit), middleware.AuthMiddleware(), middleware.RateLimitByMethod(readLimit, writeLimit))
 	{
-		protected.POST("/manga", handlers.CreateManga)
-		protected.PUT("/manga/:id", handlers.UpdateManga)
-		protected.DELETE("/manga/:id", handlers.DeleteManga)
-		protected.POST("/manga/:id/comments", handlers.AddComment)
-		protected.POST("/manga/:id/favorite", handlers.AddToFavorites)
+		protected.POST("/manga", middleware.RequirePermission(middleware.PermMangaCreate), middleware.Idempotency(), handlers.CreateManga)
+		protected.POST("/manga/batch", middleware.RequirePermission(middleware.PermMangaBatch), handlers.BatchManga)
+		protected.PUT("/manga/:id", middleware.RequirePermission(middleware.PermMangaUpdate), handlers.UpdateManga)
+		protected.PATCH("/manga/:id", middleware.RequirePermission(middleware.PermMangaUpdate), handlers.PatchManga)
+		protected.DELETE("/manga/:id", middleware.RequirePermission(middleware.PermMangaDelete), handlers.DeleteManga)
+		protected.POST("/manga/:id/revisions/:number/rollback", middleware.RequirePermission(middleware.PermMangaRollback), handlers.RollbackManga)
+		protected.POST("/manga/:id/comments", middleware.RequirePermission(middleware.PermCommentsWrite), middleware.RateLimit(commentLimit), middleware.Idempotency(), handlers.AddComment)
+		protected.POST("/manga/:id/favorite", middleware.RequirePermission(middleware.PermFavoritesWrite), middleware.Idempotency(), handlers.AddToFavorites)
 		protected.GET("/favorites", handlers.GetFavorites)
-		protected.DELETE("/manga/:id/favorite", handlers.RemoveFromFavorites)
+		protected.DELETE("/manga/:id/favorite", middleware.RequirePermission(middleware.PermFavoritesWrite), handlers.RemoveFromFavorites)
+		protected.GET("/recommendations", handlers.GetRecommendations)
+		protected.POST("/manga/:id/suggestions", middleware.RequirePermission(middleware.PermSuggestionsWrite), handlers.CreateSuggestion)
+		protected.GET("/suggestions", handlers.GetMySuggestions)
+		protected.GET("/notifications", handlers.GetNotifications)
+		protected.POST("/notifications/:id/read", handlers.MarkNotificationRead)
+
+		protected.GET("/lists", handlers.GetMyLists)
+		protected.POST("/lists", middleware.RequirePermission(middleware.PermListsWrite), handlers.CreateList)
+		protected.PUT("/lists/:id", middleware.RequirePermission(middleware.PermListsWrite), handlers.UpdateList)
+		protected.DELETE("/lists/:id", middleware.RequirePermission(middleware.PermListsWrite), handlers.DeleteList)
+		protected.POST("/lists/:id/items", middleware.RequirePermission(middleware.PermListsWrite), handlers.AddListItem)
+		protected.PUT("/lists/:id/items/:manga_id", middleware.RequirePermission(middleware.PermListsWrite), handlers.UpdateListItem)
+		protected.DELETE("/lists/:id/items/:manga_id", middleware.RequirePermission(middleware.PermListsWrite), handlers.RemoveListItem)
+		protected.PUT("/lists/:id/order", middleware.RequirePermission(middleware.PermListsWrite), handlers.ReorderListItems)
+		protected.POST("/lists/:id/copy", middleware.RequirePermission(middleware.PermListsWrite), handlers.CopyList)
+
+		protected.POST("/admin/revocations/tokens", middleware.RequirePermission(middleware.PermTokensRevoke), handlers.RevokeToken)
+		protected.POST("/admin/revocations/users/:id", middleware.RequirePermission(middleware.PermTokensRevoke), handlers.RevokeUserTokens)
+
+		protected.GET("/admin/api-keys", middleware.RequirePermission(middleware.PermAPIKeysManage), handlers.GetAPIKeys)
+		protected.POST("/admin/api-keys", middleware.RequirePermission(middleware.PermAPIKeysManage), handlers.CreateAPIKey)
+		protected.POST("/admin/api-keys/:id/rotate", middleware.RequirePermission(middleware.PermAPIKeysManage), handlers.RotateAPIKey)
+		protected.DELETE("/admin/api-keys/:id", middleware.RequirePermission(middleware.PermAPIKeysManage), handlers.RevokeAPIKey)
+		protected.GET("/admin/audit", middleware.RequirePermission(middleware.PermAuditRead), handlers.GetAuditLog)
+		protected.GET("/admin/suggestions", middleware.RequirePermission(middleware.PermSuggestionsReview), handlers.GetSuggestionQueue)
+		protected.POST("/admin/suggestions/:id/approve", middleware.RequirePermission(middleware.PermSuggestionsReview), handlers.ApproveSuggestion)
+		protected.POST("/admin/suggestions/:id/reject", middleware.RequirePermission(middleware.PermSuggestionsReview), handlers.RejectSuggestion)
+		protected.GET("/admin/trash", middleware.RequirePermission(middleware.PermTrashManage), handlers.GetTrash)
+		protected.POST("/admin/trash/:id/restore", middleware.RequirePermission(middleware.PermTrashManage), handlers.RestoreManga)
 	}
 
 	r.Run(":8080")
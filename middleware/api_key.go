@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiKeyPrefix = "mk_"
+
+// Длина публичной части ключа: "mk_" и 12 hex-символов
+const apiKeyPrefixLen = len(apiKeyPrefix) + 12
+
+// Как часто обновляем last_used_at, чтобы не писать в БД на каждый запрос
+const lastUsedResolution = time.Minute
+
+// Права, которые даёт каждая область ключа
+var scopePermissions = map[string][]string{
+	"catalog:write":     {PermMangaCreate, PermMangaUpdate, PermMangaDelete, PermMangaBatch},
+	"catalog:moderate":  {PermMangaUpdateAny, PermMangaDeleteAny, PermMangaRollback, PermSuggestionsReview},
+	"comments:write":    {PermCommentsWrite},
+	"favorites:write":   {PermFavoritesWrite},
+	"lists:write":       {PermListsWrite},
+	"suggestions:write": {PermSuggestionsWrite},
+}
+
+var errBadAPIKey = errors.New("недействительный API-ключ")
+
+func ValidScope(scope string) bool {
+	_, ok := scopePermissions[scope]
+	return ok
+}
+
+// Возвращает ключ целиком (показывается один раз), его публичный префикс и хэш для хранения
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err = rand.Read(id); err != nil {
+		return
+	}
+	if _, err = rand.Read(secret); err != nil {
+		return
+	}
+
+	prefix = apiKeyPrefix + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Ключ передаётся в X-API-Key или в Authorization: ApiKey <ключ>
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	parts := strings.Fields(c.GetHeader("Authorization"))
+	if len(parts) == 2 && strings.EqualFold(parts[0], "apikey") {
+		return parts[1]
+	}
+	return ""
+}
+
+func lookupAPIKey(key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) <= apiKeyPrefixLen || key[apiKeyPrefixLen] != '_' {
+		return nil, errBadAPIKey
+	}
+
+	var apiKey models.APIKey
+	if err := database.DB.Where("prefix = ?", key[:apiKeyPrefixLen]).First(&apiKey).Error; err != nil {
+		return nil, errBadAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hashAPIKey(key))) != 1 {
+		return nil, errBadAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now)) {
+		return nil, errBadAPIKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > lastUsedResolution {
+		go database.DB.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("last_used_at", now)
+	}
+	return &apiKey, nil
+}
+
+// Запрос от ключа выполняется от имени его пользователя, но только с правами из областей
+func apiKeyIdentity(apiKey *models.APIKey) *identity {
+	var permissions []string
+	for _, scope := range apiKey.Scopes {
+		permissions = append(permissions, scopePermissions[scope]...)
+	}
+
+	return &identity{userID: apiKey.UserID, apiKeyID: apiKey.ID, permissions: permissions}
+}
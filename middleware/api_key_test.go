@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, prefix+"_"))
+	assert.Len(t, prefix, apiKeyPrefixLen)
+	assert.Equal(t, hashAPIKey(key), hash)
+	assert.NotContains(t, hash, key)
+
+	other, _, _, _ := GenerateAPIKey()
+	assert.NotEqual(t, key, other)
+}
+
+func TestAPIKeyFromRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("Authorization", "ApiKey mk_123")
+	assert.Equal(t, "mk_123", apiKeyFromRequest(c))
+
+	c.Request.Header.Set("X-API-Key", "mk_456")
+	assert.Equal(t, "mk_456", apiKeyFromRequest(c))
+
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("Authorization", "Bearer token")
+	assert.Empty(t, apiKeyFromRequest(c))
+}
@@ -1,52 +1,186 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 )
 
-var JwtSecret = []byte("your-secret-key")
+var errBadClaims = errors.New("неверные claims")
+
+// Ставится в контекст, если запрос пришёл с недействительными учётными данными
+const authFailedKey = "auth_failed"
+
+// Кто выполняет запрос: пользователь по JWT или клиент по API-ключу
+type identity struct {
+	userID      uint
+	roles       []string
+	apiKeyID    uint
+	permissions []string
+}
+
+func (id *identity) apply(c *gin.Context) {
+	c.Set("user_id", id.userID)
+	if id.apiKeyID != 0 {
+		c.Set("api_key_id", id.apiKeyID)
+		c.Set("permissions", id.permissions)
+		return
+	}
+	c.Set("roles", id.roles)
+	c.Set("role", id.roles[0])
+}
+
+// Возвращает nil и пустое сообщение, если учётных данных в запросе нет
+func authenticate(c *gin.Context) (*identity, string) {
+	if key := apiKeyFromRequest(c); key != "" {
+		apiKey, err := lookupAPIKey(key)
+		if err != nil {
+			return nil, "Недействительный API-ключ"
+		}
+		return apiKeyIdentity(apiKey), ""
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return nil, ""
+	}
+
+	parts := strings.Fields(authHeader)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, "Неверный формат токена"
+	}
+
+	claims, err := parseToken(parts[1])
+	if err != nil {
+		return nil, "Недействительный токен"
+	}
+
+	id, err := claimsIdentity(claims)
+	if err != nil {
+		return nil, "Неверный токен (claims)"
+	}
+
+	if revocations.isRevoked(id.userID, claims) {
+		return nil, "Токен отозван"
+	}
+
+	return id, ""
+}
 
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Токен не найден"})
+		id, message := authenticate(c)
+		if id == nil {
+			if message != "" {
+				c.Set(authFailedKey, true)
+			}
+			if message == "" {
+				message = "Токен не найден"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
 			c.Abort()
 			return
 		}
 
-		parts := strings.Fields(authHeader)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный формат токена"})
-			c.Abort()
-			return
+		id.apply(c)
+		c.Next()
+	}
+}
+
+// Для публичных маршрутов: узнаёт пользователя, если он передал учётные данные,
+// а с отсутствующими или недействительными пропускает запрос как анонимный
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id, message := authenticate(c); id != nil {
+			id.apply(c)
+		} else if message != "" {
+			c.Set(authFailedKey, true)
 		}
 
-		tokenStr := parts[1]
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return JwtSecret, nil
-		}, jwt.WithValidMethods([]string{"HS256"}))
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Недействительный токен"})
-			c.Abort()
-			return
-		}
+func parseToken(tokenStr string) (jwt.MapClaims, error) {
+	if authConfig == nil {
+		return nil, errors.New("настройки JWT не загружены")
+	}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			userID := uint(claims["user_id"].(float64))
-			role := claims["role"].(string)
-			c.Set("user_id", userID)
-			c.Set("role", role)
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный токен (claims)"})
-			c.Abort()
-			return
+	token, err := jwt.Parse(tokenStr, keyFunc,
+		jwt.WithValidMethods(authConfig.validMethods()),
+		jwt.WithIssuer(authConfig.Issuer),
+		jwt.WithAudience(authConfig.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("недействительный токен")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errBadClaims
+	}
+	return claims, nil
+}
+
+// Выбирает ключ по kid, чтобы при ротации принимать токены и старым, и новым ключом
+func keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	// Асимметричные токены подписывает только user-service, ключи берём из JWKS
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		if authConfig.JWKS == nil || kid == "" {
+			return nil, errors.New("нет ключа для асимметричного токена")
 		}
+		return authConfig.JWKS.Key(kid)
+	}
 
-		c.Next()
+	if kid == "" {
+		kid = authConfig.DefaultKID
+	}
+
+	key, ok := authConfig.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный kid %q", kid)
+	}
+	return key, nil
+}
+
+func claimsIdentity(claims jwt.MapClaims) (*identity, error) {
+	rawID, ok := claims["user_id"].(float64)
+	if !ok || rawID <= 0 || rawID != math.Trunc(rawID) {
+		return nil, errBadClaims
+	}
+
+	roles := rolesFromClaims(claims)
+	if len(roles) == 0 {
+		return nil, errBadClaims
+	}
+
+	return &identity{userID: uint(rawID), roles: roles}, nil
+}
+
+// Роли берутся из массива "roles", а для старых токенов — из строки "role"
+func rolesFromClaims(claims jwt.MapClaims) []string {
+	var roles []string
+	if list, ok := claims["roles"].([]interface{}); ok {
+		for _, r := range list {
+			if role, ok := r.(string); ok && role != "" {
+				roles = append(roles, normalizeRole(role))
+			}
+		}
+	}
+	if role, ok := claims["role"].(string); ok && role != "" {
+		roles = append(roles, normalizeRole(role))
 	}
+	return roles
 }
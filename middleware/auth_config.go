@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type AuthConfig struct {
+	Issuer   string
+	Audience string
+	// Активные ключи HS256 по kid; старый ключ держим, пока не истекут выданные им токены
+	Keys map[string][]byte
+	// Ключ для токенов без заголовка kid
+	DefaultKID string
+	// Публичные ключи для RS256/ES256, если задан JWKS_URL
+	JWKS *JWKS
+}
+
+var authConfig *AuthConfig
+
+// Читает настройки JWT из окружения:
+// JWT_KEYS="kid1:secret1,kid2:secret2" или JWT_SECRET для одного ключа,
+// JWT_DEFAULT_KID, JWT_ISSUER, JWT_AUDIENCE, JWKS_URL для асимметричных токенов
+func LoadAuthConfig() error {
+	cfg := &AuthConfig{
+		Issuer:     getenv("JWT_ISSUER", "user-service"),
+		Audience:   getenv("JWT_AUDIENCE", "manga-catalog"),
+		Keys:       make(map[string][]byte),
+		DefaultKID: os.Getenv("JWT_DEFAULT_KID"),
+	}
+
+	if keys := os.Getenv("JWT_KEYS"); keys != "" {
+		for _, pair := range strings.Split(keys, ",") {
+			kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok || kid == "" || secret == "" {
+				return fmt.Errorf("неверный формат JWT_KEYS: %q", pair)
+			}
+			cfg.Keys[kid] = []byte(secret)
+			if cfg.DefaultKID == "" {
+				cfg.DefaultKID = kid
+			}
+		}
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		cfg.Keys["default"] = []byte(secret)
+		if cfg.DefaultKID == "" {
+			cfg.DefaultKID = "default"
+		}
+	}
+
+	if url := os.Getenv("JWKS_URL"); url != "" {
+		cfg.JWKS = NewJWKS(url)
+	}
+
+	if len(cfg.Keys) == 0 && cfg.JWKS == nil {
+		return errors.New("не задан ни JWT_SECRET, ни JWT_KEYS, ни JWKS_URL")
+	}
+	if _, ok := cfg.Keys[cfg.DefaultKID]; len(cfg.Keys) > 0 && !ok {
+		return fmt.Errorf("JWT_DEFAULT_KID %q не найден среди ключей", cfg.DefaultKID)
+	}
+
+	authConfig = cfg
+	return nil
+}
+
+func (cfg *AuthConfig) validMethods() []string {
+	var methods []string
+	if len(cfg.Keys) > 0 {
+		methods = append(methods, "HS256")
+	}
+	if cfg.JWKS != nil {
+		methods = append(methods, "RS256", "ES256")
+	}
+	return methods
+}
+
+// Фоновое обновление JWKS, без JWKS_URL ничего не делает
+func RefreshJWKS() error {
+	if authConfig == nil || authConfig.JWKS == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return authConfig.JWKS.Refresh(ctx)
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
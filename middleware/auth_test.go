@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuth(t *testing.T) *gin.Engine {
+	t.Setenv("JWT_KEYS", "2025-01:old-secret,2025-02:new-secret")
+	t.Setenv("JWT_DEFAULT_KID", "2025-02")
+	t.Setenv("JWT_ISSUER", "user-service")
+	t.Setenv("JWT_AUDIENCE", "manga-catalog")
+	if err := LoadAuthConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", AuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id"), "roles": Roles(c)})
+	})
+	return r
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": 1,
+		"role":    "reader",
+		"iss":     "user-service",
+		"aud":     "manga-catalog",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func sign(claims jwt.MapClaims, kid, secret string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, _ := token.SignedString([]byte(secret))
+	return s
+}
+
+func call(r *gin.Engine, token string) int {
+	req := httptest.NewRequest("GET", "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp.Code
+}
+
+func TestAuthKeyRotation(t *testing.T) {
+	r := setupAuth(t)
+
+	assert.Equal(t, http.StatusOK, call(r, sign(validClaims(), "2025-01", "old-secret")))
+	assert.Equal(t, http.StatusOK, call(r, sign(validClaims(), "2025-02", "new-secret")))
+	assert.Equal(t, http.StatusOK, call(r, sign(validClaims(), "", "new-secret")))
+	assert.Equal(t, http.StatusUnauthorized, call(r, sign(validClaims(), "2025-01", "new-secret")))
+	assert.Equal(t, http.StatusUnauthorized, call(r, sign(validClaims(), "unknown", "new-secret")))
+}
+
+func TestAuthRejectsInvalidRegisteredClaims(t *testing.T) {
+	r := setupAuth(t)
+
+	cases := map[string]func(jwt.MapClaims){
+		"missing exp":  func(c jwt.MapClaims) { delete(c, "exp") },
+		"expired":      func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"wrong issuer": func(c jwt.MapClaims) { c["iss"] = "someone-else" },
+		"wrong aud":    func(c jwt.MapClaims) { c["aud"] = "other-service" },
+	}
+
+	for name, mutate := range cases {
+		claims := validClaims()
+		mutate(claims)
+		assert.Equal(t, http.StatusUnauthorized, call(r, sign(claims, "", "new-secret")), name)
+	}
+}
+
+func TestAuthMalformedClaimsDoNotPanic(t *testing.T) {
+	r := setupAuth(t)
+
+	cases := map[string]func(jwt.MapClaims){
+		"missing user_id": func(c jwt.MapClaims) { delete(c, "user_id") },
+		"string user_id":  func(c jwt.MapClaims) { c["user_id"] = "1" },
+		"negative id":     func(c jwt.MapClaims) { c["user_id"] = -5 },
+		"missing role":    func(c jwt.MapClaims) { delete(c, "role") },
+		"numeric role":    func(c jwt.MapClaims) { c["role"] = 42 },
+	}
+
+	for name, mutate := range cases {
+		claims := validClaims()
+		mutate(claims)
+		assert.Equal(t, http.StatusUnauthorized, call(r, sign(claims, "", "new-secret")), name)
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	setupAuth(t)
+	r := gin.New()
+	r.GET("/", OptionalAuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")})
+	})
+
+	get := func(token string) (int, string) {
+		req := httptest.NewRequest("GET", "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code, resp.Body.String()
+	}
+
+	code, body := get("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"user_id": 0}`, body)
+
+	code, body = get(sign(validClaims(), "", "new-secret"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"user_id": 1}`, body)
+
+	code, body = get("garbage")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"user_id": 0}`, body)
+}
@@ -0,0 +1,366 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Через сколько незавершённый запрос считается брошенным (например, реплика упала),
+// и его ключ можно захватить повторно
+const idempotencyLockTimeout = time.Minute
+
+// Заголовки ответа, которые нужны клиенту при повторе
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+type StoredResponse struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+type IdempotencyRecord struct {
+	RequestHash string
+	Completed   bool
+	Response    StoredResponse
+}
+
+type IdempotencyStore interface {
+	// Захватывает ключ под новый запрос. Если ключ уже занят, возвращает его запись и false
+	Acquire(key, hash string, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	Complete(key string, response StoredResponse) error
+	// Освобождает ключ, если ответ сохранять не нужно
+	Release(key string) error
+	Cleanup() error
+}
+
+var (
+	idempotencyStore IdempotencyStore = NewMemoryIdempotencyStore()
+	idempotencyTTL                    = 24 * time.Hour
+)
+
+// IDEMPOTENCY_TTL — сколько хранится ответ (например, 24h);
+// IDEMPOTENCY_STORE=memory хранит ключи в памяти процесса, по умолчанию — в Postgres
+func LoadIdempotencyConfig() error {
+	idempotencyTTL = 24 * time.Hour
+	if raw := os.Getenv("IDEMPOTENCY_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("неверный IDEMPOTENCY_TTL %q", raw)
+		}
+		idempotencyTTL = ttl
+	}
+
+	switch store := os.Getenv("IDEMPOTENCY_STORE"); store {
+	case "", "postgres":
+		idempotencyStore = NewPostgresIdempotencyStore(database.DB)
+	case "memory":
+		idempotencyStore = NewMemoryIdempotencyStore()
+	default:
+		return fmt.Errorf("неизвестный IDEMPOTENCY_STORE %q", store)
+	}
+	return nil
+}
+
+func CleanupIdempotencyKeys() error {
+	return idempotencyStore.Cleanup()
+}
+
+// Ключи разных пользователей не пересекаются
+func idempotencyKey(c *gin.Context, key string) string {
+	return strconv.FormatUint(uint64(c.GetUint("user_id")), 10) + ":" + key
+}
+
+// Хэш метода, пути и тела: тот же ключ с другим запросом — ошибка клиента
+func requestHash(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Запоминает тело ответа, чтобы сохранить его для повторов
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func replay(c *gin.Context, response StoredResponse) {
+	for name, value := range response.Headers {
+		c.Header(name, value)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(response.Status)
+	c.Writer.Write(response.Body)
+}
+
+// Ответы, которые зависят от момента запроса, а не от него самого: их не сохраняем,
+// иначе повтор с тем же ключом до конца TTL получал бы устаревший отказ
+func transientStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusLocked,
+		http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= http.StatusInternalServerError
+}
+
+// Повтор запроса с тем же Idempotency-Key получает сохранённый первый ответ,
+// а не выполняется заново. Без заголовка запрос проходит как обычно
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key длиннее 255 символов"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать тело запроса"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := idempotencyKey(c, key)
+		hash := requestHash(c, body)
+		record, acquired, err := idempotencyStore.Acquire(storeKey, hash, idempotencyTTL)
+		if err != nil {
+			// Недоступное хранилище не должно ронять API
+			log.Printf("Ошибка хранилища ключей идемпотентности: %v", err)
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			switch {
+			case record.RequestHash != hash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key уже использован с другим запросом"})
+			case !record.Completed:
+				c.Header("Retry-After", "1")
+				c.JSON(http.StatusConflict, gin.H{"error": "Запрос с этим Idempotency-Key ещё выполняется"})
+			default:
+				replay(c, record.Response)
+			}
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		completed := false
+		defer func() {
+			// Паника или временная ошибка: ключ освобождаем, чтобы клиент мог повторить
+			if !completed {
+				if err := idempotencyStore.Release(storeKey); err != nil {
+					log.Printf("Ошибка освобождения ключа идемпотентности: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		if transientStatus(writer.Status()) {
+			return
+		}
+
+		response := StoredResponse{
+			Status:  writer.Status(),
+			Headers: make(map[string]string),
+			Body:    writer.body.Bytes(),
+		}
+		for _, name := range replayedHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				response.Headers[name] = value
+			}
+		}
+		if err := idempotencyStore.Complete(storeKey, response); err != nil {
+			log.Printf("Ошибка сохранения ответа для ключа идемпотентности: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+type memoryIdempotencyEntry struct {
+	record    IdempotencyRecord
+	lockedAt  time.Time
+	expiresAt time.Time
+}
+
+// Хранит ключи в памяти процесса: повторы видит только та же реплика
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryIdempotencyEntry
+	now     func() time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries: make(map[string]*memoryIdempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Acquire(key, hash string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if e, ok := s.entries[key]; ok {
+		abandoned := !e.record.Completed && e.record.RequestHash == hash && now.Sub(e.lockedAt) >= idempotencyLockTimeout
+		if now.Before(e.expiresAt) && !abandoned {
+			record := e.record
+			return &record, false, nil
+		}
+	}
+
+	s.entries[key] = &memoryIdempotencyEntry{
+		record:    IdempotencyRecord{RequestHash: hash},
+		lockedAt:  now,
+		expiresAt: now.Add(ttl),
+	}
+	return nil, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, response StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.record.Completed = true
+		e.record.Response = response
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Cleanup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+// Хранит ключи в Postgres, чтобы повтор, попавший на другую реплику, тоже получил сохранённый ответ
+type PostgresIdempotencyStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresIdempotencyStore(db *gorm.DB) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{db: db}
+}
+
+func (s *PostgresIdempotencyStore) Acquire(key, hash string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	// Вставка и перезахват просроченного или брошенного ключа — одним запросом,
+	// чтобы две реплики не захватили ключ одновременно
+	var acquired []string
+	err := s.db.Raw(`
+		INSERT INTO idempotency_keys AS k (key, request_hash, locked_at, expires_at)
+		VALUES (@key, @hash, now(), now() + @ttl * interval '1 second')
+		ON CONFLICT (key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			completed = FALSE,
+			response_status = 0,
+			response_headers = NULL,
+			response_body = NULL,
+			locked_at = now(),
+			expires_at = EXCLUDED.expires_at
+		WHERE k.expires_at <= now()
+			OR (NOT k.completed AND k.request_hash = EXCLUDED.request_hash
+				AND k.locked_at < now() - @lock * interval '1 second')
+		RETURNING key`,
+		sql.Named("key", key),
+		sql.Named("hash", hash),
+		sql.Named("ttl", ttl.Seconds()),
+		sql.Named("lock", idempotencyLockTimeout.Seconds()),
+	).Scan(&acquired).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(acquired) > 0 {
+		return nil, true, nil
+	}
+
+	var row models.IdempotencyKey
+	if err := s.db.Where("key = ?", key).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Ключ освободили между вставкой и чтением — пусть клиент повторит
+			return &IdempotencyRecord{RequestHash: hash}, false, nil
+		}
+		return nil, false, err
+	}
+	return &IdempotencyRecord{
+		RequestHash: row.RequestHash,
+		Completed:   row.Completed,
+		Response: StoredResponse{
+			Status:  row.ResponseStatus,
+			Headers: row.ResponseHeaders,
+			Body:    row.ResponseBody,
+		},
+	}, false, nil
+}
+
+func (s *PostgresIdempotencyStore) Complete(key string, response StoredResponse) error {
+	return s.db.Model(&models.IdempotencyKey{Key: key}).
+		Select("completed", "response_status", "response_headers", "response_body").
+		Updates(&models.IdempotencyKey{
+			Completed:       true,
+			ResponseStatus:  response.Status,
+			ResponseHeaders: response.Headers,
+			ResponseBody:    response.Body,
+		}).Error
+}
+
+func (s *PostgresIdempotencyStore) Release(key string) error {
+	return s.db.Where("key = ? AND NOT completed", key).Delete(&models.IdempotencyKey{}).Error
+}
+
+func (s *PostgresIdempotencyStore) Cleanup() error {
+	return s.db.Where("expires_at <= now()").Delete(&models.IdempotencyKey{}).Error
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupIdempotency(t *testing.T) *MemoryIdempotencyStore {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryIdempotencyStore()
+	prevStore, prevTTL := idempotencyStore, idempotencyTTL
+	idempotencyStore, idempotencyTTL = store, time.Hour
+	t.Cleanup(func() { idempotencyStore, idempotencyTTL = prevStore, prevTTL })
+	return store
+}
+
+func TestIdempotencyReplaysFirstResponse(t *testing.T) {
+	setupIdempotency(t)
+
+	calls := 0
+	r := gin.New()
+	r.POST("/manga", func(c *gin.Context) { c.Set("user_id", uint(1)) }, Idempotency(), func(c *gin.Context) {
+		calls++
+		c.Header("ETag", `"1.1"`)
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/manga", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	first := send("k1", `{"title":"A"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := send("k1", `{"title":"A"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, `"1.1"`, retry.Header().Get("ETag"))
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, send("k1", `{"title":"B"}`).Code)
+	assert.Equal(t, http.StatusCreated, send("", `{"title":"A"}`).Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeysArePerUser(t *testing.T) {
+	setupIdempotency(t)
+
+	calls := 0
+	r := gin.New()
+	r.POST("/manga", func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.GetHeader("X-User"))
+		c.Set("user_id", uint(id))
+	}, Idempotency(), func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusCreated)
+	})
+
+	for _, user := range []string{"1", "2"} {
+		req := httptest.NewRequest("POST", "/manga", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "same")
+		req.Header.Set("X-User", user)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	setupIdempotency(t)
+
+	fail := true
+	r := gin.New()
+	r.POST("/manga", Idempotency(), func(c *gin.Context) {
+		if fail {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	send := func() int {
+		req := httptest.NewRequest("POST", "/manga", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "k")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, send())
+	fail = false
+	assert.Equal(t, http.StatusCreated, send())
+}
+
+func TestIdempotencyDoesNotStoreTransientResponses(t *testing.T) {
+	setupIdempotency(t)
+	rateStore = NewMemoryRateStore()
+	t.Cleanup(func() { rateStore = NewMemoryRateStore() })
+
+	calls := 0
+	status := http.StatusConflict
+	r := gin.New()
+	r.POST("/comments", RateLimit(RatePolicy{Name: "comments", Limit: 1, Per: time.Hour}), Idempotency(), func(c *gin.Context) {
+		calls++
+		c.Status(status)
+	})
+
+	send := func() int {
+		req := httptest.NewRequest("POST", "/comments", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "k")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	assert.Equal(t, http.StatusConflict, send())
+	// Лимит исчерпан: 429 отдаёт лимитер, обработчик и хранилище ключей не вызываются
+	assert.Equal(t, http.StatusTooManyRequests, send())
+	assert.Equal(t, 1, calls)
+
+	rateStore = NewMemoryRateStore()
+	status = http.StatusTooManyRequests
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	rateStore = NewMemoryRateStore()
+	status = http.StatusCreated
+	assert.Equal(t, http.StatusCreated, send())
+	assert.Equal(t, 3, calls)
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryIdempotencyStore()
+	store.now = func() time.Time { return now }
+
+	_, acquired, _ := store.Acquire("k", "h", time.Hour)
+	assert.True(t, acquired)
+
+	// Пока первый запрос выполняется, повтор получает незавершённую запись
+	record, acquired, _ := store.Acquire("k", "h", time.Hour)
+	assert.False(t, acquired)
+	assert.False(t, record.Completed)
+
+	// Брошенный запрос можно перезахватить тем же телом, но не другим
+	now = now.Add(idempotencyLockTimeout)
+	_, acquired, _ = store.Acquire("k", "other", time.Hour)
+	assert.False(t, acquired)
+	_, acquired, _ = store.Acquire("k", "h", time.Hour)
+	assert.True(t, acquired)
+
+	store.Complete("k", StoredResponse{Status: http.StatusCreated})
+	record, acquired, _ = store.Acquire("k", "h", time.Hour)
+	assert.False(t, acquired)
+	assert.True(t, record.Completed)
+	assert.Equal(t, http.StatusCreated, record.Response.Status)
+
+	now = now.Add(time.Hour)
+	store.Cleanup()
+	assert.Empty(t, store.entries)
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Не чаще этого ходим за JWKS при появлении неизвестного kid, в том числе после неудачной попытки
+const jwksMinRefreshInterval = 30 * time.Second
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Кэш публичных ключей user-service, загружаемых из JWKS
+type JWKS struct {
+	url    string
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	lastAttempt time.Time
+	// Закрывается, когда текущее обновление по неизвестному kid завершится
+	inflight chan struct{}
+}
+
+func NewJWKS(url string) *JWKS {
+	return &JWKS{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   make(map[string]interface{}),
+	}
+}
+
+func (j *JWKS) Refresh(ctx context.Context) error {
+	// Время попытки фиксируем до запроса, чтобы недоступный JWKS не дёргали на каждый токен
+	j.mu.Lock()
+	j.lastAttempt = time.Now()
+	j.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS вернул статус %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return err
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		// Новый тип ключа у провайдера не должен ломать проверку остальных
+		key, err := k.publicKey()
+		if err != nil {
+			log.Printf("JWKS: пропускаем ключ %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+	return nil
+}
+
+// Возвращает ключ по kid; если его нет, один раз принудительно перечитывает JWKS.
+// Одновременные запросы с неизвестным kid ждут одно общее обновление
+func (j *JWKS) Key(kid string) (interface{}, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	j.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	j.mu.Lock()
+	if key, ok := j.keys[kid]; ok {
+		j.mu.Unlock()
+		return key, nil
+	}
+
+	if wait := j.inflight; wait != nil {
+		j.mu.Unlock()
+		<-wait
+	} else {
+		if time.Since(j.lastAttempt) < jwksMinRefreshInterval {
+			j.mu.Unlock()
+			return nil, fmt.Errorf("неизвестный kid %q", kid)
+		}
+		wait = make(chan struct{})
+		j.inflight = wait
+		j.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := j.Refresh(ctx)
+		cancel()
+
+		j.mu.Lock()
+		j.inflight = nil
+		close(wait)
+		j.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("неизвестный kid %q", kid)
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("неподдерживаемая кривая %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("точка не лежит на кривой")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип ключа %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jwksServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	keys  []map[string]string
+	calls atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) add(key map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+}
+
+func b64(i *big.Int, size int) string {
+	b := i.Bytes()
+	if size > 0 {
+		b = i.FillBytes(make([]byte, size))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kid": kid, "kty": "RSA", "use": "sig", "alg": "RS256",
+		"n": b64(key.N, 0), "e": b64(big.NewInt(int64(key.E)), 0),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kid": kid, "kty": "EC", "crv": "P-256", "alg": "ES256",
+		"x": b64(key.X, 32), "y": b64(key.Y, 32),
+	}
+}
+
+func signWith(method jwt.SigningMethod, kid string, key interface{}) string {
+	token := jwt.NewWithClaims(method, validClaims())
+	token.Header["kid"] = kid
+	s, _ := token.SignedString(key)
+	return s
+}
+
+func setupJWKSAuth(t *testing.T, url string) *gin.Engine {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("JWKS_URL", url)
+	require.NoError(t, LoadAuthConfig())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestJWKSVerifiesRSAAndEC(t *testing.T) {
+	server := newJWKSServer(t)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	server.add(rsaJWK("rsa-1", rsaKey))
+	server.add(ecJWK("ec-1", ecKey))
+
+	r := setupJWKSAuth(t, server.URL)
+
+	assert.Equal(t, http.StatusOK, call(r, signWith(jwt.SigningMethodRS256, "rsa-1", rsaKey)))
+	assert.Equal(t, http.StatusOK, call(r, signWith(jwt.SigningMethodES256, "ec-1", ecKey)))
+
+	// Подпись чужим ключом с известным kid
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Equal(t, http.StatusUnauthorized, call(r, signWith(jwt.SigningMethodRS256, "rsa-1", otherKey)))
+
+	// HS256 без настроенного секрета не принимается
+	assert.Equal(t, http.StatusUnauthorized, call(r, sign(validClaims(), "rsa-1", "secret")))
+}
+
+func TestJWKSRefreshesOnUnknownKid(t *testing.T) {
+	server := newJWKSServer(t)
+	first, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server.add(rsaJWK("k1", first))
+
+	r := setupJWKSAuth(t, server.URL)
+	assert.Equal(t, http.StatusOK, call(r, signWith(jwt.SigningMethodRS256, "k1", first)))
+	assert.Equal(t, int32(1), server.calls.Load())
+
+	// Ключ появился после последнего обновления: ждём окончания троттлинга
+	second, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server.add(rsaJWK("k2", second))
+	assert.Equal(t, http.StatusUnauthorized, call(r, signWith(jwt.SigningMethodRS256, "k2", second)))
+	assert.Equal(t, int32(1), server.calls.Load())
+
+	authConfig.JWKS.mu.Lock()
+	authConfig.JWKS.lastAttempt = time.Now().Add(-jwksMinRefreshInterval)
+	authConfig.JWKS.mu.Unlock()
+
+	assert.Equal(t, http.StatusOK, call(r, signWith(jwt.SigningMethodRS256, "k2", second)))
+	assert.Equal(t, int32(2), server.calls.Load())
+}
+
+func TestRefreshJWKS(t *testing.T) {
+	server := newJWKSServer(t)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	server.add(ecJWK("ec-1", key))
+	setupJWKSAuth(t, server.URL)
+
+	require.NoError(t, RefreshJWKS())
+	_, err := authConfig.JWKS.Key("ec-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), server.calls.Load())
+}
+
+func TestJWKSSkipsUnsupportedKeys(t *testing.T) {
+	server := newJWKSServer(t)
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server.add(map[string]string{"kid": "okp-1", "kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"})
+	server.add(map[string]string{"kid": "ec-521", "kty": "EC", "crv": "P-521", "x": "AQ", "y": "AQ"})
+	server.add(rsaJWK("rsa-1", key))
+
+	r := setupJWKSAuth(t, server.URL)
+	assert.Equal(t, http.StatusOK, call(r, signWith(jwt.SigningMethodRS256, "rsa-1", key)))
+}
+
+func TestJWKSDownIsNotHammered(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	r := setupJWKSAuth(t, server.URL)
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	// Одновременные токены с неизвестным kid ждут одно обновление
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusUnauthorized, call(r, signWith(jwt.SigningMethodRS256, "unknown", key)))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Неудачная попытка тоже включает троттлинг
+	start := time.Now()
+	assert.Equal(t, http.StatusUnauthorized, call(r, signWith(jwt.SigningMethodRS256, "other", key)))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+}
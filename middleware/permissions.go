@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RoleReader    = "reader"
+	RoleUploader  = "uploader"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+const (
+	PermMangaCreate       = "manga:create"
+	PermMangaUpdate       = "manga:update"
+	PermMangaUpdateAny    = "manga:update:any"
+	PermMangaDelete       = "manga:delete"
+	PermMangaDeleteAny    = "manga:delete:any"
+	PermMangaRollback     = "manga:rollback"
+	PermMangaBatch        = "manga:batch"
+	PermCommentsWrite     = "comments:write"
+	PermFavoritesWrite    = "favorites:write"
+	PermListsWrite        = "lists:write"
+	PermSuggestionsWrite  = "suggestions:write"
+	PermSuggestionsReview = "suggestions:review"
+	PermTokensRevoke      = "tokens:revoke"
+	PermAPIKeysManage     = "api_keys:manage"
+	PermAuditRead         = "audit:read"
+	PermTrashManage       = "trash:manage"
+)
+
+var readerPermissions = []string{
+	PermCommentsWrite,
+	PermFavoritesWrite,
+	PermListsWrite,
+	PermSuggestionsWrite,
+}
+
+var uploaderPermissions = append([]string{
+	PermMangaCreate,
+	PermMangaUpdate,
+	PermMangaDelete,
+	PermMangaBatch,
+}, readerPermissions...)
+
+var moderatorPermissions = append([]string{
+	PermMangaUpdateAny,
+	PermMangaDeleteAny,
+	PermMangaRollback,
+	PermSuggestionsReview,
+}, uploaderPermissions...)
+
+var adminPermissions = append([]string{
+	PermTokensRevoke,
+	PermAPIKeysManage,
+	PermAuditRead,
+	PermTrashManage,
+}, moderatorPermissions...)
+
+var rolePermissions = map[string][]string{
+	RoleReader:    readerPermissions,
+	RoleUploader:  uploaderPermissions,
+	RoleModerator: moderatorPermissions,
+	RoleAdmin:     adminPermissions,
+}
+
+// Старые токены выдавались с ролью "user"
+var roleAliases = map[string]string{
+	"user": RoleReader,
+}
+
+func normalizeRole(role string) string {
+	if alias, ok := roleAliases[role]; ok {
+		return alias
+	}
+	return role
+}
+
+func Roles(c *gin.Context) []string {
+	roles, _ := c.Get("roles")
+	list, _ := roles.([]string)
+	return list
+}
+
+func HasRole(c *gin.Context, roles ...string) bool {
+	for _, have := range Roles(c) {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func HasPermission(c *gin.Context, permission string) bool {
+	// У API-ключей нет ролей, права заданы областями ключа
+	for _, p := range c.GetStringSlice("permissions") {
+		if p == permission {
+			return true
+		}
+	}
+
+	for _, role := range Roles(c) {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
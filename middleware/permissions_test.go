@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func withRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("roles", roles)
+		c.Next()
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		roles []string
+		perm  string
+		want  int
+	}{
+		{[]string{RoleReader}, PermMangaCreate, http.StatusForbidden},
+		{[]string{RoleReader}, PermCommentsWrite, http.StatusOK},
+		{[]string{RoleUploader}, PermMangaCreate, http.StatusOK},
+		{[]string{RoleUploader}, PermMangaUpdateAny, http.StatusForbidden},
+		{[]string{RoleReader, RoleModerator}, PermMangaUpdateAny, http.StatusOK},
+		{[]string{RoleAdmin}, PermMangaDeleteAny, http.StatusOK},
+		{[]string{RoleUploader}, PermMangaRollback, http.StatusForbidden},
+		{[]string{RoleModerator}, PermMangaRollback, http.StatusOK},
+		{[]string{RoleReader}, PermMangaBatch, http.StatusForbidden},
+		{[]string{RoleUploader}, PermMangaBatch, http.StatusOK},
+		{nil, PermCommentsWrite, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		r := gin.New()
+		r.GET("/", withRoles(tc.roles...), RequirePermission(tc.perm), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, tc.want, resp.Code, "roles %v, permission %s", tc.roles, tc.perm)
+	}
+}
+
+func TestRolesFromClaims(t *testing.T) {
+	claims := jwt.MapClaims{
+		"roles": []interface{}{"uploader", "moderator"},
+		"role":  "user",
+	}
+
+	assert.Equal(t, []string{RoleUploader, RoleModerator, RoleReader}, rolesFromClaims(claims))
+}
@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"manga-catalog/database"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Корзина на Limit запросов, которая полностью наполняется за Per
+type RatePolicy struct {
+	Name  string
+	Limit int
+	Per   time.Duration
+}
+
+func (p RatePolicy) rate() float64 {
+	return float64(p.Limit) / p.Per.Seconds()
+}
+
+type RateResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	// Через сколько корзина снова будет полной
+	Reset time.Duration
+}
+
+type RateStore interface {
+	Take(key string, policy RatePolicy) (RateResult, error)
+	// Как Take, но без списания: можно ли сейчас сделать запрос
+	Peek(key string, policy RatePolicy) (RateResult, error)
+	Cleanup() error
+}
+
+var rateStore RateStore = NewMemoryRateStore()
+
+// RATE_LIMIT_STORE=postgres включает общий для всех реплик счётчик в БД
+func LoadRateLimitConfig() error {
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", "memory":
+		rateStore = NewMemoryRateStore()
+	case "postgres":
+		rateStore = NewPostgresRateStore(database.DB)
+	default:
+		return fmt.Errorf("неизвестный RATE_LIMIT_STORE %q", store)
+	}
+	return nil
+}
+
+func CleanupRateLimits() error {
+	return rateStore.Cleanup()
+}
+
+func result(policy RatePolicy, allowed bool, tokens float64) RateResult {
+	rate := policy.rate()
+	res := RateResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(policy.Limit) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+// Ключ лимита: пользователь, если он известен, иначе IP клиента.
+// ClientIP берёт X-Forwarded-For только от доверенных прокси, см. TRUSTED_PROXIES в main.go
+func rateKey(c *gin.Context, policy RatePolicy) string {
+	if userID := c.GetUint("user_id"); userID != 0 {
+		return policy.Name + ":u:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return ipRateKey(c, policy)
+}
+
+func ipRateKey(c *gin.Context, policy RatePolicy) string {
+	return policy.Name + ":ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func rejectTooMany(c *gin.Context, res RateResult) {
+	c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Слишком много запросов"})
+	c.Abort()
+}
+
+func RateLimit(policy RatePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := rateStore.Take(rateKey(c, policy), policy)
+		if err != nil {
+			// Недоступное хранилище не должно ронять API
+			log.Printf("Ошибка rate limiter (%s): %v", policy.Name, err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Limit, ceilSeconds(policy.Per)))
+		h.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+		if !res.Allowed {
+			rejectTooMany(c, res)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GET и HEAD считаются по лимиту read, остальные методы — по write
+func RateLimitByMethod(read, write RatePolicy) gin.HandlerFunc {
+	readLimit, writeLimit := RateLimit(read), RateLimit(write)
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			readLimit(c)
+		default:
+			writeLimit(c)
+		}
+	}
+}
+
+// Ограничивает неудачные попытки аутентификации с одного IP. Ставится перед AuthMiddleware:
+// когда лимит исчерпан, запрос отклоняется ещё до проверки токена или поиска API-ключа в БД
+func AuthFailureLimit(policy RatePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := ipRateKey(c, policy)
+		res, err := rateStore.Peek(key, policy)
+		if err != nil {
+			log.Printf("Ошибка rate limiter (%s): %v", policy.Name, err)
+		} else if !res.Allowed {
+			rejectTooMany(c, res)
+			return
+		}
+
+		c.Next()
+
+		if c.GetBool(authFailedKey) {
+			if _, err := rateStore.Take(key, policy); err != nil {
+				log.Printf("Ошибка rate limiter (%s): %v", policy.Name, err)
+			}
+		}
+	}
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Хранит корзины в памяти процесса: лимит считается отдельно на каждой реплике
+type MemoryRateStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	limits  map[string]RatePolicy
+	now     func() time.Time
+}
+
+func NewMemoryRateStore() *MemoryRateStore {
+	return &MemoryRateStore{
+		buckets: make(map[string]*bucket),
+		limits:  make(map[string]RatePolicy),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateStore) Take(key string, policy RatePolicy) (RateResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Limit), updated: now}
+		s.buckets[key] = b
+		s.limits[key] = policy
+	}
+
+	b.tokens = math.Min(float64(policy.Limit), b.tokens+now.Sub(b.updated).Seconds()*policy.rate())
+	b.updated = now
+
+	if b.tokens < 1 {
+		return result(policy, false, b.tokens), nil
+	}
+	b.tokens--
+	return result(policy, true, b.tokens), nil
+}
+
+func (s *MemoryRateStore) Peek(key string, policy RatePolicy) (RateResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		return result(policy, true, float64(policy.Limit)), nil
+	}
+	tokens := math.Min(float64(policy.Limit), b.tokens+s.now().Sub(b.updated).Seconds()*policy.rate())
+	return result(policy, tokens >= 1, tokens), nil
+}
+
+// Удаляет корзины, которые уже успели наполниться: они не отличаются от новых
+func (s *MemoryRateStore) Cleanup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= s.limits[key].Per {
+			delete(s.buckets, key)
+			delete(s.limits, key)
+		}
+	}
+	return nil
+}
+
+// Хранит корзины в Postgres, чтобы все реплики видели один лимит.
+// Пополнение и списание делаются одним атомарным UPSERT.
+type PostgresRateStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresRateStore(db *gorm.DB) *PostgresRateStore {
+	return &PostgresRateStore{db: db}
+}
+
+func (s *PostgresRateStore) Take(key string, policy RatePolicy) (RateResult, error) {
+	// В SET все выражения видят старую строку, поэтому пополнение считается один раз от прежних значений
+	refilled := "LEAST(@limit, b.tokens + EXTRACT(EPOCH FROM (now() - b.updated_at)) * @rate)"
+
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := s.db.Raw(fmt.Sprintf(`
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES (@key, @limit - 1, TRUE, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE WHEN %[1]s >= 1 THEN %[1]s - 1 ELSE %[1]s END,
+			allowed = %[1]s >= 1,
+			updated_at = now()
+		RETURNING tokens, allowed`, refilled),
+		sql.Named("key", key),
+		sql.Named("limit", policy.Limit),
+		sql.Named("rate", policy.rate()),
+	).Scan(&row).Error
+	if err != nil {
+		return RateResult{}, err
+	}
+	return result(policy, row.Allowed, row.Tokens), nil
+}
+
+func (s *PostgresRateStore) Peek(key string, policy RatePolicy) (RateResult, error) {
+	var tokens []float64
+	err := s.db.Raw(`
+		SELECT LEAST(@limit, tokens + EXTRACT(EPOCH FROM (now() - updated_at)) * @rate)
+		FROM rate_limit_buckets WHERE key = @key`,
+		sql.Named("key", key),
+		sql.Named("limit", policy.Limit),
+		sql.Named("rate", policy.rate()),
+	).Scan(&tokens).Error
+	if err != nil {
+		return RateResult{}, err
+	}
+	if len(tokens) == 0 {
+		return result(policy, true, float64(policy.Limit)), nil
+	}
+	return result(policy, tokens[0] >= 1, tokens[0]), nil
+}
+
+func (s *PostgresRateStore) Cleanup() error {
+	return s.db.Exec("DELETE FROM rate_limit_buckets WHERE updated_at < now() - interval '1 day'").Error
+}
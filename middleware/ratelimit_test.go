@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateStoreRefills(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryRateStore()
+	store.now = func() time.Time { return now }
+	policy := RatePolicy{Name: "test", Limit: 2, Per: 10 * time.Second}
+
+	res, _ := store.Take("k", policy)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, _ = store.Take("k", policy)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, _ = store.Take("k", policy)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 5*time.Second, res.RetryAfter)
+
+	// Другой ключ не зависит от первого
+	res, _ = store.Take("other", policy)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(5 * time.Second)
+	res, _ = store.Take("k", policy)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(time.Minute)
+	store.Cleanup()
+	assert.Empty(t, store.buckets)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rateStore = NewMemoryRateStore()
+	t.Cleanup(func() { rateStore = NewMemoryRateStore() })
+
+	r := gin.New()
+	r.GET("/", RateLimit(RatePolicy{Name: "reads", Limit: 1, Per: time.Minute}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	get := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := get("10.0.0.1")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "1", resp.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+
+	resp = get("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, get("10.0.0.2").Code)
+}
+
+func TestAuthFailureLimit(t *testing.T) {
+	setupAuth(t)
+	rateStore = NewMemoryRateStore()
+	t.Cleanup(func() { rateStore = NewMemoryRateStore() })
+
+	r := gin.New()
+	r.SetTrustedProxies(nil)
+	r.GET("/", AuthFailureLimit(RatePolicy{Name: "auth-failures", Limit: 2, Per: time.Minute}), AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	forwarded := 0
+	get := func(ip, token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("Authorization", "Bearer "+token)
+		// Без доверенных прокси подмена X-Forwarded-For не даёт новую корзину
+		forwarded++
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", forwarded))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+	valid := sign(validClaims(), "", "new-secret")
+
+	assert.Equal(t, http.StatusOK, get("10.0.0.1", valid))
+	assert.Equal(t, http.StatusUnauthorized, get("10.0.0.1", "bad"))
+	assert.Equal(t, http.StatusUnauthorized, get("10.0.0.1", "bad"))
+	// Успешные запросы лимит не расходуют, но после исчерпания отклоняется всё до проверки токена
+	assert.Equal(t, http.StatusTooManyRequests, get("10.0.0.1", valid))
+	assert.Equal(t, http.StatusOK, get("10.0.0.2", valid))
+}
+
+func TestRateLimitByMethod(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rateStore = NewMemoryRateStore()
+	t.Cleanup(func() { rateStore = NewMemoryRateStore() })
+
+	r := gin.New()
+	limit := RateLimitByMethod(RatePolicy{Name: "reads", Limit: 2, Per: time.Minute}, RatePolicy{Name: "writes", Limit: 1, Per: time.Minute})
+	r.Any("/", limit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest(method, "/", nil))
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, do("POST").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("DELETE").Code)
+	resp := do("GET")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+}
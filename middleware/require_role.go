@@ -5,11 +5,10 @@ import (
 	"net/http"
 )
 
-func RequireRole(requiredRole string) gin.HandlerFunc {
+// Пропускает пользователя, у которого есть хотя бы одна из ролей
+func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role := c.GetString("role")
-
-		if role != requiredRole {
+		if !HasRole(c, roles...) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен"})
 			c.Abort()
 			return
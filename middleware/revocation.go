@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Копия списка отзыва в памяти, чтобы не ходить в БД на каждый запрос.
+// Другие реплики узнают об отзыве при следующем ReloadRevocations.
+type revocationCache struct {
+	mu      sync.RWMutex
+	tokens  map[string]time.Time
+	cutoffs map[uint]time.Time
+}
+
+var revocations = &revocationCache{
+	tokens:  make(map[string]time.Time),
+	cutoffs: make(map[uint]time.Time),
+}
+
+func (r *revocationCache) addToken(jti string, expiresAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[jti] = expiresAt
+}
+
+func (r *revocationCache) setCutoff(userID uint, before time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cutoffs[userID] = before
+}
+
+func (r *revocationCache) isRevoked(userID uint, claims jwt.MapClaims) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		if _, revoked := r.tokens[jti]; revoked {
+			return true
+		}
+	}
+
+	cutoff, ok := r.cutoffs[userID]
+	if !ok {
+		return false
+	}
+	// Без iat нельзя доказать, что токен выдан после отзыва
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return true
+	}
+	// iat хранится с точностью до секунды: токен, выданный в ту же секунду, что и отзыв, считаем новым
+	return issuedAt.Unix() < cutoff.Unix()
+}
+
+// Перечитывает список отзыва из БД, истёкшие токены удаляет
+func ReloadRevocations() error {
+	if err := database.DB.Where("expires_at < now()").Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	var tokens []models.RevokedToken
+	if err := database.DB.Select("jti", "expires_at").Find(&tokens).Error; err != nil {
+		return err
+	}
+	var cutoffs []models.UserTokenRevocation
+	if err := database.DB.Select("user_id", "revoked_before").Find(&cutoffs).Error; err != nil {
+		return err
+	}
+
+	fresh := &revocationCache{
+		tokens:  make(map[string]time.Time, len(tokens)),
+		cutoffs: make(map[uint]time.Time, len(cutoffs)),
+	}
+	for _, t := range tokens {
+		fresh.tokens[t.JTI] = t.ExpiresAt
+	}
+	for _, c := range cutoffs {
+		fresh.cutoffs[c.UserID] = c.RevokedBefore
+	}
+
+	revocations.mu.Lock()
+	revocations.tokens = fresh.tokens
+	revocations.cutoffs = fresh.cutoffs
+	revocations.mu.Unlock()
+	return nil
+}
+
+func RevokeToken(db *gorm.DB, token *models.RevokedToken) error {
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+	if err != nil {
+		return err
+	}
+	revocations.addToken(token.JTI, token.ExpiresAt)
+	return nil
+}
+
+func RevokeUserTokens(db *gorm.DB, revocation *models.UserTokenRevocation) error {
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "reason", "revoked_by", "updated_at"}),
+	}).Create(revocation).Error
+	if err != nil {
+		return err
+	}
+	revocations.setCutoff(revocation.UserID, revocation.RevokedBefore)
+	return nil
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedTokensAreRejected(t *testing.T) {
+	r := setupAuth(t)
+	t.Cleanup(func() {
+		revocations = &revocationCache{tokens: map[string]time.Time{}, cutoffs: map[uint]time.Time{}}
+	})
+
+	claims := validClaims()
+	claims["jti"] = "token-1"
+	claims["iat"] = time.Now().Unix()
+	token := sign(claims, "", "new-secret")
+	assert.Equal(t, http.StatusOK, call(r, token))
+
+	revocations.addToken("token-1", time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusUnauthorized, call(r, token))
+}
+
+func TestUserCutoffRejectsOlderTokens(t *testing.T) {
+	r := setupAuth(t)
+	t.Cleanup(func() {
+		revocations = &revocationCache{tokens: map[string]time.Time{}, cutoffs: map[uint]time.Time{}}
+	})
+
+	old := validClaims()
+	old["iat"] = time.Now().Add(-time.Hour).Unix()
+	fresh := validClaims()
+	fresh["iat"] = time.Now().Add(time.Minute).Unix()
+	noIat := validClaims()
+
+	revocations.setCutoff(1, time.Now())
+
+	assert.Equal(t, http.StatusUnauthorized, call(r, sign(old, "", "new-secret")))
+	assert.Equal(t, http.StatusUnauthorized, call(r, sign(noIat, "", "new-secret")))
+	assert.Equal(t, http.StatusOK, call(r, sign(fresh, "", "new-secret")))
+}
+
+func TestUserCutoffAcceptsTokenFromSameSecond(t *testing.T) {
+	r := setupAuth(t)
+	t.Cleanup(func() {
+		revocations = &revocationCache{tokens: map[string]time.Time{}, cutoffs: map[uint]time.Time{}}
+	})
+
+	// Отзыв в середине секунды, новый вход в ту же секунду
+	second := time.Now().Truncate(time.Second)
+	revocations.setCutoff(1, second.Add(700*time.Millisecond))
+
+	claims := validClaims()
+	claims["iat"] = second.Unix()
+	assert.Equal(t, http.StatusOK, call(r, sign(claims, "", "new-secret")))
+
+	claims["iat"] = second.Add(-time.Second).Unix()
+	assert.Equal(t, http.StatusUnauthorized, call(r, sign(claims, "", "new-secret")))
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Счётчик событий по манге за один часовой интервал
+type MangaActivity struct {
+	MangaID uint      `gorm:"primaryKey" json:"manga_id"`
+	Bucket  time.Time `gorm:"primaryKey" json:"bucket"`
+	Kind    string    `gorm:"primaryKey" json:"kind"`
+	Count   int       `json:"count"`
+}
+
+// Посчитанный агрегатором рейтинг манги в окне времени
+type MangaTrending struct {
+	TimeWindow string    `gorm:"primaryKey" json:"window"`
+	MangaID    uint      `gorm:"primaryKey" json:"manga_id"`
+	Score      float64   `json:"score"`
+	ComputedAt time.Time `json:"computed_at"`
+}
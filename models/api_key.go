@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Ключ для ботов и партнёров; сам ключ не хранится, только его sha256
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	Scopes     []string   `gorm:"serializer:json" json:"scopes"`
+	UserID     uint       `json:"user_id"`
+	CreatedBy  uint       `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
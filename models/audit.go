@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Запись журнала аудита; таблица только на добавление, изменять записи запрещает триггер
+type AuditEntry struct {
+	ID         uint                   `gorm:"primaryKey" json:"id"`
+	ActorID    uint                   `json:"actor_id"`
+	APIKeyID   *uint                  `gorm:"column:api_key_id" json:"api_key_id,omitempty"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   uint                   `json:"entity_id"`
+	RequestID  string                 `json:"request_id"`
+	ClientIP   string                 `json:"client_ip"`
+	Before     map[string]interface{} `gorm:"serializer:json" json:"before"`
+	After      map[string]interface{} `gorm:"serializer:json" json:"after"`
+	Diff       map[string]interface{} `gorm:"serializer:json" json:"diff"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
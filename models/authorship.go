@@ -0,0 +1,20 @@
+package models
+
+// Кто создал запись и кто менял её последним; время хранится в CreatedAt/UpdatedAt
+type Authorship struct {
+	CreatedBy *uint `json:"created_by"`
+	UpdatedBy *uint `json:"updated_by"`
+}
+
+func (a *Authorship) SetCreator(userID uint) {
+	a.CreatedBy = &userID
+	a.UpdatedBy = &userID
+}
+
+func (a *Authorship) SetUpdater(userID uint) {
+	a.UpdatedBy = &userID
+}
+
+func (a Authorship) IsCreatedBy(userID uint) bool {
+	return a.CreatedBy != nil && *a.CreatedBy == userID
+}
@@ -1,7 +1,10 @@
 package models
 
+import "time"
+
 type Favorite struct {
-	ID      uint `gorm:"primaryKey"`
-	UserID  uint `json:"user_id"`
-	MangaID uint `json:"manga_id"`
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `json:"user_id"`
+	MangaID   uint      `json:"manga_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Ключ идемпотентности и первый ответ на запрос с ним
+type IdempotencyKey struct {
+	Key             string `gorm:"primaryKey"`
+	RequestHash     string
+	Completed       bool
+	ResponseStatus  int
+	ResponseHeaders map[string]string `gorm:"serializer:json"`
+	ResponseBody    []byte
+	LockedAt        time.Time
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	ListPrivate  = "private"
+	ListUnlisted = "unlisted"
+	ListPublic   = "public"
+)
+
+type List struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `json:"user_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Visibility  string     `json:"visibility"`
+	ShareToken  string     `json:"share_token,omitempty"`
+	Items       []ListItem `gorm:"foreignKey:ListID" json:"items,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type ListItem struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	ListID   uint   `json:"list_id"`
+	MangaID  uint   `json:"manga_id"`
+	Position int    `json:"position"`
+	Note     string `json:"note"`
+	Manga    *Manga `gorm:"foreignKey:MangaID" json:"manga,omitempty"`
+}
@@ -1,8 +1,46 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Состояния публикации манги
+const (
+	MangaDraft     = "draft"
+	MangaScheduled = "scheduled"
+	MangaPublished = "published"
+	MangaUnlisted  = "unlisted" // доступна по прямой ссылке, но не попадает в каталог
+)
+
 type Manga struct {
-	ID          uint   `gorm:"primaryKey"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Genre       string `json:"genre"`
+	ID             uint      `gorm:"primaryKey"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	Genre          string    `json:"genre"`
+	FavoritesCount int64     `gorm:"not null;default:0" json:"favorites_count"`
+	ViewsCount     int64     `gorm:"not null;default:0" json:"views_count"`
+	Version        int64     `gorm:"not null;default:1" json:"version"` // растёт при каждом изменении полей
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Status         string    `gorm:"not null;default:published" json:"status"`
+	// Для scheduled — когда опубликовать, после публикации — когда это произошло
+	PublishAt *time.Time `json:"publish_at"`
+	// Удалённая манга лежит в корзине до окончательной очистки
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	Authorship
+}
+
+func ValidMangaStatus(status string) bool {
+	switch status {
+	case MangaDraft, MangaScheduled, MangaPublished, MangaUnlisted:
+		return true
+	}
+	return false
+}
+
+// Видна ли манга тем, кто не может её редактировать
+func (m Manga) IsVisible() bool {
+	return m.Status == MangaPublished || m.Status == MangaUnlisted
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Уведомление пользователю, например о решении по его предложению
+type Notification struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `json:"user_id"`
+	Type       string     `json:"type"`
+	Message    string     `json:"message"`
+	EntityType string     `json:"entity_type"`
+	EntityID   uint       `json:"entity_id"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
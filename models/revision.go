@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Снимок редактируемых полей манги после очередного изменения
+type MangaRevision struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	MangaID     uint      `json:"manga_id"`
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Genre       string    `json:"genre"`
+	Status      string    `json:"status"` // видно в diff, но откат статус не меняет
+	AuthorID    uint      `json:"author_id"`
+	RollbackOf  *int      `json:"rollback_of,omitempty"` // к какой ревизии откатились
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Поля ревизии, которые сравниваются и восстанавливаются
+func (r MangaRevision) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		"title":       r.Title,
+		"description": r.Description,
+		"genre":       r.Genre,
+		"status":      r.Status,
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Отозванный токен; запись нужна только до истечения самого токена
+type RevokedToken struct {
+	JTI       string    `gorm:"column:jti;primaryKey" json:"jti"`
+	UserID    uint      `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Reason    string    `json:"reason"`
+	RevokedBy uint      `json:"revoked_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Все токены пользователя, выданные раньше RevokedBefore, недействительны
+type UserTokenRevocation struct {
+	UserID        uint      `gorm:"primaryKey" json:"user_id"`
+	RevokedBefore time.Time `json:"revoked_before"`
+	Reason        string    `json:"reason"`
+	RevokedBy     uint      `json:"revoked_by"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type MangaSimilar struct {
+	MangaID   uint    `gorm:"primaryKey" json:"manga_id"`
+	SimilarID uint    `gorm:"primaryKey" json:"similar_id"`
+	Score     float64 `json:"score"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	SuggestionPending  = "pending"
+	SuggestionApproved = "approved"
+	SuggestionRejected = "rejected"
+)
+
+// Предложенная читателем правка манги; nil — поле не меняется
+type MangaSuggestion struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	MangaID     uint       `json:"manga_id"`
+	UserID      uint       `json:"user_id"`
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Genre       *string    `json:"genre,omitempty"`
+	Comment     string     `json:"comment"`
+	BaseVersion int64      `json:"base_version"` // версия манги, к которой сделано предложение
+	Status      string     `gorm:"not null;default:pending" json:"status"`
+	ReviewerID  *uint      `json:"reviewer_id,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,312 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Патч не разбирается или в нём неизвестная операция
+	ErrInvalidPatch = errors.New("некорректный патч")
+	// Патч корректен, но к документу не применяется
+	ErrPathNotFound = errors.New("путь не найден")
+	ErrTestFailed   = errors.New("проверка test не прошла")
+)
+
+// Операция JSON Patch (RFC 6902)
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Применяет JSON Merge Patch (RFC 7396): null удаляет ключ, объекты сливаются рекурсивно
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = merge(t[key], value)
+		}
+	}
+	return t
+}
+
+// Применяет JSON Patch (RFC 6902); операции выполняются по порядку, при ошибке документ не меняется
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if root, err = apply(root, op); err != nil {
+			return nil, fmt.Errorf("операция %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func apply(root interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: нет value", ErrInvalidPatch)
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			return replace(root, path, value)
+		}
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+
+	case "remove":
+		root, _, err := remove(root, path)
+		return root, err
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			// Нельзя переместить объект внутрь самого себя
+			if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+				return nil, fmt.Errorf("%w: from является префиксом path", ErrInvalidPatch)
+			}
+			root, value, err := remove(root, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(root, path, value)
+		}
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, deepCopy(value))
+	}
+
+	return nil, fmt.Errorf("%w: неизвестная операция %q", ErrInvalidPatch, op.Op)
+}
+
+// Разбирает JSON Pointer (RFC 6901); пустая строка — весь документ
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: путь %q должен начинаться с /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Индекс массива: без ведущих нулей и знаков, "-" обрабатывается вызывающим кодом
+func arrayIndex(token string, length int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: неверный индекс %q", ErrPathNotFound, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i >= length {
+		return 0, fmt.Errorf("%w: индекс %q вне массива", ErrPathNotFound, token)
+	}
+	return i, nil
+}
+
+func child(node interface{}, token string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		value, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+		}
+		return value, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n))
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+}
+
+func get(root interface{}, path []string) (interface{}, error) {
+	node := root
+	for _, token := range path {
+		var err error
+		if node, err = child(node, token); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// Спускается до родителя последнего токена, меняет его через fn и пересобирает путь обратно:
+// append может вернуть новый срез, поэтому родителей нужно обновлять
+func update(node interface{}, path []string, fn func(parent interface{}, last string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	next, err := child(node, path[0])
+	if err != nil {
+		return nil, err
+	}
+	next, err = update(next, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[path[0]] = next
+	case []interface{}:
+		i, _ := arrayIndex(path[0], len(n))
+		n[i] = next
+	}
+	return node, nil
+}
+
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(parent interface{}, last string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[last] = value
+			return p, nil
+		case []interface{}:
+			if last == "-" {
+				return append(p, value), nil
+			}
+			i, err := arrayIndex(last, len(p)+1)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("%w: %q", ErrPathNotFound, last)
+	})
+}
+
+func remove(root interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: нельзя удалить весь документ", ErrInvalidPatch)
+	}
+
+	var removed interface{}
+	root, err := update(root, path, func(parent interface{}, last string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			value, ok := p[last]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrPathNotFound, last)
+			}
+			removed = value
+			delete(p, last)
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(last, len(p))
+			if err != nil {
+				return nil, err
+			}
+			removed = p[i]
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: %q", ErrPathNotFound, last)
+	})
+	return root, removed, err
+}
+
+func replace(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if _, err := get(root, path); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(parent interface{}, last string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[last] = value
+		case []interface{}:
+			i, _ := arrayIndex(last, len(p))
+			p[i] = value
+		}
+		return parent, nil
+	})
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = deepCopy(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = deepCopy(item)
+		}
+		return s
+	}
+	return value
+}
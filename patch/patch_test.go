@@ -0,0 +1,86 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Примеры из приложения A RFC 7396
+	cases := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tc := range cases {
+		got, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+		if assert.NoError(t, err) {
+			assert.JSONEq(t, tc.want, string(got), "doc %s, patch %s", tc.doc, tc.patch)
+		}
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestJSONPatch(t *testing.T) {
+	cases := []struct{ doc, patch, want string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"foo":{"a":1}}`, `[{"op":"copy","from":"/foo","path":"/bar"},{"op":"replace","path":"/bar/a","value":2}]`, `{"foo":{"a":1},"bar":{"a":2}}`},
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"add","path":"/ok","value":true}]`, `{"baz":"qux","ok":true}`},
+		{`{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/m~0n"}]`, `{}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+	}
+
+	for _, tc := range cases {
+		got, err := JSONPatch([]byte(tc.doc), []byte(tc.patch))
+		if assert.NoError(t, err, "patch %s", tc.patch) {
+			assert.JSONEq(t, tc.want, string(got), "doc %s, patch %s", tc.doc, tc.patch)
+		}
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	cases := []struct {
+		doc, patch string
+		want       error
+	}{
+		{`{}`, `{"op":"add"}`, ErrInvalidPatch},
+		{`{}`, `[{"op":"frobnicate","path":"/a"}]`, ErrInvalidPatch},
+		{`{}`, `[{"op":"add","path":"/a"}]`, ErrInvalidPatch},
+		{`{}`, `[{"op":"add","path":"a","value":1}]`, ErrInvalidPatch},
+		{`{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, ErrInvalidPatch},
+		{`{}`, `[{"op":"remove","path":"/missing"}]`, ErrPathNotFound},
+		{`{}`, `[{"op":"replace","path":"/missing","value":1}]`, ErrPathNotFound},
+		{`{"a":[1]}`, `[{"op":"add","path":"/a/5","value":1}]`, ErrPathNotFound},
+		{`{"a":[1]}`, `[{"op":"remove","path":"/a/01"}]`, ErrPathNotFound},
+		{`{"a":"b"}`, `[{"op":"test","path":"/a","value":"c"}]`, ErrTestFailed},
+	}
+
+	for _, tc := range cases {
+		_, err := JSONPatch([]byte(tc.doc), []byte(tc.patch))
+		assert.ErrorIs(t, err, tc.want, "patch %s", tc.patch)
+	}
+}
@@ -0,0 +1,103 @@
+package recommend
+
+import (
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"math"
+	"sync"
+)
+
+// Сколько соседей храним для каждого тайтла
+const neighbourLimit = 50
+
+var (
+	mu         sync.RWMutex
+	neighbours map[uint][]Scored
+)
+
+// Косинусная похожесть тайтлов по множествам пользователей, добавивших их в избранное
+func ItemNeighbours(libraries map[uint][]uint, limit int) map[uint][]Scored {
+	popularity := make(map[uint]int)
+	co := make(map[uint]map[uint]int)
+
+	for _, items := range libraries {
+		for i, a := range items {
+			popularity[a]++
+			for _, b := range items[i+1:] {
+				if a == b {
+					continue
+				}
+				if co[a] == nil {
+					co[a] = make(map[uint]int)
+				}
+				if co[b] == nil {
+					co[b] = make(map[uint]int)
+				}
+				co[a][b]++
+				co[b][a]++
+			}
+		}
+	}
+
+	result := make(map[uint][]Scored, len(co))
+	for a, others := range co {
+		scored := make([]Scored, 0, len(others))
+		for b, n := range others {
+			score := float64(n) / math.Sqrt(float64(popularity[a]*popularity[b]))
+			scored = append(scored, Scored{ID: b, Score: score})
+		}
+		result[a] = top(scored, limit)
+	}
+	return result
+}
+
+// Складывает похожесть соседей всех тайтлов библиотеки, исключая уже добавленные
+func Recommend(model map[uint][]Scored, library []uint, limit int) []Scored {
+	owned := make(map[uint]bool, len(library))
+	for _, id := range library {
+		owned[id] = true
+	}
+
+	scores := make(map[uint]float64)
+	for _, id := range library {
+		for _, n := range model[id] {
+			if !owned[n.ID] {
+				scores[n.ID] += n.Score
+			}
+		}
+	}
+
+	scored := make([]Scored, 0, len(scores))
+	for id, score := range scores {
+		scored = append(scored, Scored{ID: id, Score: score})
+	}
+	return top(scored, limit)
+}
+
+// Пересобирает модель по таблице избранного. Оценок в каталоге пока нет,
+// поэтому сигналом служит только факт добавления в избранное.
+func RebuildCollaborative() error {
+	var favorites []models.Favorite
+	if err := database.DB.Select("user_id", "manga_id").Find(&favorites).Error; err != nil {
+		return err
+	}
+
+	libraries := make(map[uint][]uint)
+	for _, f := range favorites {
+		libraries[f.UserID] = append(libraries[f.UserID], f.MangaID)
+	}
+
+	model := ItemNeighbours(libraries, neighbourLimit)
+
+	mu.Lock()
+	neighbours = model
+	mu.Unlock()
+	return nil
+}
+
+// Рекомендации для библиотеки пользователя по последней собранной модели
+func ForLibrary(library []uint, limit int) []Scored {
+	mu.RLock()
+	defer mu.RUnlock()
+	return Recommend(neighbours, library, limit)
+}
@@ -0,0 +1,40 @@
+package recommend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemNeighbours(t *testing.T) {
+	libraries := map[uint][]uint{
+		1: {10, 20},
+		2: {10, 20},
+		3: {10, 30},
+	}
+
+	model := ItemNeighbours(libraries, 10)
+
+	if assert.Len(t, model[10], 2) {
+		assert.Equal(t, uint(20), model[10][0].ID)
+		assert.Greater(t, model[10][0].Score, model[10][1].Score)
+	}
+	assert.Empty(t, model[40])
+}
+
+func TestRecommendExcludesLibrary(t *testing.T) {
+	libraries := map[uint][]uint{
+		1: {10, 20, 30},
+		2: {10, 20},
+		3: {20, 40},
+	}
+	model := ItemNeighbours(libraries, 10)
+
+	recs := Recommend(model, []uint{10, 20}, 10)
+
+	ids := make([]uint, len(recs))
+	for i, r := range recs {
+		ids[i] = r.ID
+	}
+	assert.ElementsMatch(t, []uint{30, 40}, ids)
+}
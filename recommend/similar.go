@@ -0,0 +1,222 @@
+package recommend
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Вклад каждого признака в итоговую похожесть
+const (
+	genreWeight       = 0.4
+	descriptionWeight = 0.6
+)
+
+// Признаки манги для похожести. Тегов и авторов произведения в модели нет
+// (Authorship — это загрузивший пользователь), поэтому сравниваем только жанры и описание
+type Document struct {
+	ID          uint
+	Genres      []string
+	Description string
+}
+
+type Scored struct {
+	ID    uint
+	Score float64
+}
+
+// Жанры хранятся одной строкой, допускаем перечисление через запятую или слэш
+func SplitGenres(genre string) []string {
+	parts := strings.FieldsFunc(genre, func(r rune) bool { return r == ',' || r == '/' || r == ';' })
+	genres := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			genres = append(genres, p)
+		}
+	}
+	return genres
+}
+
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) >= 3 {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// Нормализованные TF-IDF векторы описаний
+func tfidf(docs []Document) []map[string]float64 {
+	df := make(map[string]int)
+	tfs := make([]map[string]float64, len(docs))
+	for i, d := range docs {
+		tf := make(map[string]float64)
+		for _, t := range tokenize(d.Description) {
+			tf[t]++
+		}
+		for t := range tf {
+			df[t]++
+		}
+		tfs[i] = tf
+	}
+
+	n := float64(len(docs))
+	for _, tf := range tfs {
+		var norm float64
+		for t, f := range tf {
+			w := f * math.Log(1+n/float64(df[t]))
+			tf[t] = w
+			norm += w * w
+		}
+		norm = math.Sqrt(norm)
+		for t := range tf {
+			tf[t] /= norm
+		}
+	}
+	return tfs
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var dot float64
+	for t, w := range a {
+		dot += w * b[t]
+	}
+	return dot
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, x := range a {
+		set[x] = true
+	}
+	inter := 0
+	union := len(set)
+	for _, x := range b {
+		if set[x] {
+			inter++
+		} else {
+			union++
+		}
+	}
+	return float64(inter) / float64(union)
+}
+
+func score(a, b Document, va, vb map[string]float64) float64 {
+	return genreWeight*jaccard(a.Genres, b.Genres) + descriptionWeight*cosine(va, vb)
+}
+
+// До limit самых похожих на docs[i]
+func row(docs []Document, vectors []map[string]float64, i, limit int) []Scored {
+	var scored []Scored
+	for j, b := range docs {
+		if i == j {
+			continue
+		}
+		if s := score(docs[i], b, vectors[i], vectors[j]); s > 0 {
+			scored = append(scored, Scored{ID: b.ID, Score: s})
+		}
+	}
+	return top(scored, limit)
+}
+
+// Для каждого документа возвращает до limit самых похожих на него
+func Similar(docs []Document, limit int) map[uint][]Scored {
+	vectors := tfidf(docs)
+	result := make(map[uint][]Scored, len(docs))
+	for i, a := range docs {
+		result[a.ID] = row(docs, vectors, i, limit)
+	}
+	return result
+}
+
+// Обновляет посчитанные ранее списки current после изменения документов changed
+// и возвращает только те списки, которые поменялись. Изменённые документы и те, у кого
+// они уже были в списке, пересчитываются целиком, остальным достаточно сравнить
+// новую оценку с имеющимися. Документ из changed, которого нет в docs, считается удалённым.
+// IDF берётся по текущему каталогу, а старые оценки не пересчитываются, поэтому
+// результат может немного отличаться от полного Similar
+func UpdateSimilar(docs []Document, current map[uint][]Scored, changed []uint, limit int) map[uint][]Scored {
+	vectors := tfidf(docs)
+	index := make(map[uint]int, len(docs))
+	for i, d := range docs {
+		index[d.ID] = i
+	}
+	isChanged := make(map[uint]bool, len(changed))
+	for _, id := range changed {
+		isChanged[id] = true
+	}
+
+	result := make(map[uint][]Scored)
+	for _, id := range changed {
+		if i, ok := index[id]; ok {
+			result[id] = row(docs, vectors, i, limit)
+		} else {
+			result[id] = nil
+		}
+	}
+
+	for j, y := range docs {
+		if isChanged[y.ID] {
+			continue
+		}
+		list := current[y.ID]
+		stale := false
+		for _, s := range list {
+			if isChanged[s.ID] {
+				stale = true
+				break
+			}
+		}
+		if stale {
+			result[y.ID] = row(docs, vectors, j, limit)
+			continue
+		}
+
+		merged := append([]Scored(nil), list...)
+		for _, id := range changed {
+			i, ok := index[id]
+			if !ok {
+				continue
+			}
+			if s := score(y, docs[i], vectors[j], vectors[i]); s > 0 {
+				merged = append(merged, Scored{ID: id, Score: s})
+			}
+		}
+		if len(merged) == len(list) {
+			continue
+		}
+		merged = top(merged, limit)
+		for _, s := range merged {
+			if isChanged[s.ID] {
+				result[y.ID] = merged
+				break
+			}
+		}
+	}
+	return result
+}
+
+func top(scored []Scored, limit int) []Scored {
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].ID < scored[j].ID
+	})
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+	return scored
+}
@@ -0,0 +1,88 @@
+package recommend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitGenres(t *testing.T) {
+	assert.Equal(t, []string{"action", "fantasy"}, SplitGenres("Action, Fantasy"))
+	assert.Equal(t, []string{"drama"}, SplitGenres("Drama"))
+	assert.Empty(t, SplitGenres(""))
+}
+
+func TestSimilarRanksByGenreAndDescription(t *testing.T) {
+	docs := []Document{
+		{ID: 1, Genres: []string{"action"}, Description: "Young ninja trains to become the village leader"},
+		{ID: 2, Genres: []string{"action"}, Description: "Ninja clan war over the hidden village"},
+		{ID: 3, Genres: []string{"action"}, Description: "Pirates search for the legendary treasure"},
+		{ID: 4, Genres: []string{"romance"}, Description: "Office workers fall in love"},
+	}
+
+	similar := Similar(docs, 10)
+
+	if assert.NotEmpty(t, similar[1]) {
+		assert.Equal(t, uint(2), similar[1][0].ID)
+	}
+	for _, s := range similar[1] {
+		assert.NotEqual(t, uint(4), s.ID)
+	}
+}
+
+func TestSimilarRespectsLimit(t *testing.T) {
+	docs := []Document{
+		{ID: 1, Genres: []string{"action"}},
+		{ID: 2, Genres: []string{"action"}},
+		{ID: 3, Genres: []string{"action"}},
+	}
+
+	assert.Len(t, Similar(docs, 1)[1], 1)
+}
+
+func TestUpdateSimilarTouchesOnlyAffectedRows(t *testing.T) {
+	docs := []Document{
+		{ID: 1, Genres: []string{"action"}, Description: "Young ninja trains to become the village leader"},
+		{ID: 2, Genres: []string{"action"}, Description: "Ninja clan war over the hidden village"},
+		{ID: 3, Genres: []string{"romance"}, Description: "Office workers fall in love"},
+		{ID: 4, Genres: []string{"comedy"}, Description: "Cooking club tries new recipes"},
+	}
+	current := Similar(docs, 10)
+
+	// Третий тайтл переписали в историю про ниндзя
+	docs[2] = Document{ID: 3, Genres: []string{"action"}, Description: "Ninja leader defends the hidden village"}
+	updated := UpdateSimilar(docs, current, []uint{3}, 10)
+
+	if assert.NotEmpty(t, updated[3]) {
+		assert.NotEqual(t, uint(4), updated[3][0].ID)
+	}
+	assert.Contains(t, updated, uint(1))
+	assert.Contains(t, updated, uint(2))
+	// У четвёртого нет ничего общего ни со старой, ни с новой версией
+	assert.NotContains(t, updated, uint(4))
+
+	full := Similar(docs, 10)
+	ids := func(scored []Scored) []uint {
+		out := []uint{}
+		for _, s := range scored {
+			out = append(out, s.ID)
+		}
+		return out
+	}
+	assert.Equal(t, ids(full[3]), ids(updated[3]))
+}
+
+func TestUpdateSimilarRemovesDeleted(t *testing.T) {
+	docs := []Document{
+		{ID: 1, Genres: []string{"action"}},
+		{ID: 2, Genres: []string{"action"}},
+		{ID: 3, Genres: []string{"action"}},
+	}
+	current := Similar(docs, 10)
+
+	updated := UpdateSimilar(docs[:2], current, []uint{3}, 10)
+
+	assert.Contains(t, updated, uint(3))
+	assert.Empty(t, updated[3])
+	assert.Equal(t, []Scored{{ID: 2, Score: genreWeight}}, updated[1])
+}
@@ -0,0 +1,135 @@
+package recommend
+
+import (
+	"log"
+	"manga-catalog/database"
+	"manga-catalog/models"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Сколько похожих тайтлов храним для каждой манги
+const similarLimit = 20
+
+var (
+	refresh   = make(chan struct{}, 1)
+	pendingMu sync.Mutex
+	pending   = make(map[uint]bool)
+)
+
+// Просит фоновый воркер пересчитать похожие тайтлы для изменённой манги, не блокируя вызывающего
+func RefreshSimilar(ids ...uint) {
+	pendingMu.Lock()
+	for _, id := range ids {
+		pending[id] = true
+	}
+	pendingMu.Unlock()
+
+	select {
+	case refresh <- struct{}{}:
+	default:
+	}
+}
+
+func takePending() []uint {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	ids := make([]uint, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	pending = make(map[uint]bool)
+	return ids
+}
+
+// Обрабатывает запросы на пересчёт; несколько запросов подряд схлопываются в один
+func RunSimilarWorker() {
+	for range refresh {
+		ids := takePending()
+		if len(ids) == 0 {
+			continue
+		}
+		if err := updateSimilar(ids); err != nil {
+			log.Printf("Ошибка пересчёта похожих тайтлов: %v", err)
+		}
+	}
+}
+
+func loadDocuments() ([]Document, error) {
+	var manga []models.Manga
+	if err := database.DB.Select("id", "genre", "description").Find(&manga).Error; err != nil {
+		return nil, err
+	}
+
+	docs := make([]Document, len(manga))
+	for i, m := range manga {
+		docs[i] = Document{ID: m.ID, Genres: SplitGenres(m.Genre), Description: m.Description}
+	}
+	return docs, nil
+}
+
+// Записывает списки похожих; старые строки вызывающий удаляет сам
+func saveSimilar(tx *gorm.DB, similar map[uint][]Scored) error {
+	var rows []models.MangaSimilar
+	for id, scored := range similar {
+		for _, s := range scored {
+			rows = append(rows, models.MangaSimilar{MangaID: id, SimilarID: s.ID, Score: s.Score})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+// Пересчитывает похожие только для изменённых тайтлов и тех, чьи списки они затрагивают;
+// неточности накопленного IDF исправляет периодический RebuildSimilar
+func updateSimilar(ids []uint) error {
+	docs, err := loadDocuments()
+	if err != nil {
+		return err
+	}
+
+	var existing []models.MangaSimilar
+	if err := database.DB.Find(&existing).Error; err != nil {
+		return err
+	}
+	current := make(map[uint][]Scored)
+	for _, e := range existing {
+		current[e.MangaID] = append(current[e.MangaID], Scored{ID: e.SimilarID, Score: e.Score})
+	}
+
+	updated := UpdateSimilar(docs, current, ids, similarLimit)
+	if len(updated) == 0 {
+		return nil
+	}
+	changedIDs := make([]uint, 0, len(updated))
+	for id := range updated {
+		changedIDs = append(changedIDs, id)
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("manga_id IN ?", changedIDs).Delete(&models.MangaSimilar{}).Error; err != nil {
+			return err
+		}
+		return saveSimilar(tx, updated)
+	})
+}
+
+// Пересчитывает таблицу похожих тайтлов по всему каталогу
+func RebuildSimilar() error {
+	docs, err := loadDocuments()
+	if err != nil {
+		return err
+	}
+	similar := Similar(docs, similarLimit)
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM manga_similars").Error; err != nil {
+			return err
+		}
+		return saveSimilar(tx, similar)
+	})
+}
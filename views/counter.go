@@ -0,0 +1,75 @@
+package views
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+const Manga = "manga"
+
+type Target struct {
+	Kind string
+	ID   uint
+}
+
+// Считает просмотры в памяти: повторный просмотр тем же зрителем внутри окна не учитывается
+type Counter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	seen    map[string]time.Time
+	pending map[Target]int
+	now     func() time.Time
+}
+
+func NewCounter(window time.Duration) *Counter {
+	return &Counter{
+		window:  window,
+		seen:    make(map[string]time.Time),
+		pending: make(map[Target]int),
+		now:     time.Now,
+	}
+}
+
+// Возвращает true, если просмотр засчитан
+func (c *Counter) Hit(target Target, viewer string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := target.Kind + ":" + strconv.FormatUint(uint64(target.ID), 10) + "|" + viewer
+	now := c.now()
+	if last, ok := c.seen[key]; ok && now.Sub(last) < c.window {
+		return false
+	}
+
+	c.seen[key] = now
+	c.pending[target]++
+	return true
+}
+
+// Забирает накопленные просмотры и чистит устаревшие записи дедупликации
+func (c *Counter) Drain() map[Target]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, at := range c.seen {
+		if now.Sub(at) >= c.window {
+			delete(c.seen, key)
+		}
+	}
+
+	drained := c.pending
+	c.pending = make(map[Target]int)
+	return drained
+}
+
+// Возвращает просмотры, которые не удалось записать, чтобы не потерять их
+func (c *Counter) Restore(pending map[Target]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for target, n := range pending {
+		c.pending[target] += n
+	}
+}
@@ -0,0 +1,37 @@
+package views
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterDeduplicatesWithinWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewCounter(30 * time.Minute)
+	c.now = func() time.Time { return now }
+	target := Target{Kind: Manga, ID: 1}
+
+	assert.True(t, c.Hit(target, "u:1"))
+	assert.False(t, c.Hit(target, "u:1"))
+	assert.True(t, c.Hit(target, "ip:10.0.0.1"))
+
+	now = now.Add(31 * time.Minute)
+	assert.True(t, c.Hit(target, "u:1"))
+
+	assert.Equal(t, map[Target]int{target: 3}, c.Drain())
+	assert.Empty(t, c.Drain())
+}
+
+func TestCounterRestore(t *testing.T) {
+	c := NewCounter(time.Minute)
+	target := Target{Kind: Manga, ID: 2}
+	c.Hit(target, "u:1")
+
+	pending := c.Drain()
+	c.Restore(pending)
+	c.Hit(target, "u:2")
+
+	assert.Equal(t, map[Target]int{target: 2}, c.Drain())
+}
@@ -0,0 +1,57 @@
+package views
+
+import (
+	"fmt"
+	"manga-catalog/activity"
+	"manga-catalog/database"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Таблица, в которой хранится счётчик просмотров для каждого вида объекта
+var tables = map[string]string{
+	Manga: "mangas",
+}
+
+var Default = NewCounter(dedupWindow())
+
+func dedupWindow() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("VIEWS_DEDUP_WINDOW")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Minute
+}
+
+// Записывает накопленные просмотры одной транзакцией.
+// Просмотры, не успевшие попасть в БД до остановки сервиса, теряются.
+func Flush() error {
+	pending := Default.Drain()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for target, n := range pending {
+			table, ok := tables[target.Kind]
+			if !ok {
+				continue
+			}
+			err := tx.Exec(fmt.Sprintf("UPDATE %s SET views_count = views_count + ? WHERE id = ?", table), n, target.ID).Error
+			if err != nil {
+				return err
+			}
+			if target.Kind == Manga {
+				if err := activity.Record(tx, target.ID, activity.View, n); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		Default.Restore(pending)
+	}
+	return err
+}